@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Asset is one CMDB record joined to a host by address or hostname
+type Asset struct {
+	IP          string    `json:"ip,omitempty"`
+	Hostname    string    `json:"hostname,omitempty"`
+	Owner       string    `json:"owner,omitempty"`
+	Environment string    `json:"environment,omitempty"`
+	Criticality string    `json:"criticality,omitempty"` // low, medium, high or critical
+	Tags        assetTags `json:"tags,omitempty"`
+}
+
+// assetTags accepts a JSON array or a single "a;b;c" string
+type assetTags []string
+
+func (t *assetTags) UnmarshalJSON(b []byte) error {
+	var list []string
+	if err := json.Unmarshal(b, &list); err == nil {
+		*t = cleanTags(list)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("tags: want a list or a string")
+	}
+	*t = splitTags(s)
+	return nil
+}
+
+// splitTags splits a CSV tags cell on ";", "|" or ","
+func splitTags(s string) []string {
+	return cleanTags(strings.FieldsFunc(s, func(r rune) bool { return r == ';' || r == '|' || r == ',' }))
+}
+
+func cleanTags(tags []string) []string {
+	var out []string
+	for _, t := range tags {
+		if t = strings.TrimSpace(t); t != "" && !containsString(out, t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// criticalityWeights scale a host's risk score by business criticality
+var criticalityWeights = map[string]float64{
+	RiskLow:      0.5,
+	RiskMedium:   1,
+	RiskHigh:     1.5,
+	RiskCritical: 2,
+}
+
+// DataTags is the data-tags attribute value, "|"-delimited so tags may contain spaces
+func (a Asset) DataTags() string {
+	if len(a.Tags) == 0 {
+		return ""
+	}
+	return "|" + strings.Join(a.Tags, "|") + "|"
+}
+
+// AssetInventory indexes the -assets records by address and hostname
+type AssetInventory struct {
+	byIP   map[string]*Asset
+	byName map[string]*Asset
+	Tags   []string // every tag in the file, sorted
+}
+
+// assetInventory is the loaded -assets file, nil when none was given
+var assetInventory *AssetInventory
+
+// csvColumns maps accepted CSV header names onto Asset fields
+var csvColumns = map[string]string{
+	"ip":                   "ip",
+	"address":              "ip",
+	"addr":                 "ip",
+	"ip_address":           "ip",
+	"hostname":             "hostname",
+	"host":                 "hostname",
+	"name":                 "hostname",
+	"fqdn":                 "hostname",
+	"owner":                "owner",
+	"environment":          "environment",
+	"env":                  "environment",
+	"criticality":          "criticality",
+	"business_criticality": "criticality",
+	"tags":                 "tags",
+}
+
+// loadAssetFile reads a CSV file with a header row, or a JSON array of
+// records; the format is picked from the first character
+func loadAssetFile(filename string) (*AssetInventory, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var assets []Asset
+	if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &assets); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", filename, err)
+		}
+	} else if assets, err = parseAssetCSV(bytes.NewReader(b)); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", filename, err)
+	}
+
+	inv := &AssetInventory{byIP: make(map[string]*Asset), byName: make(map[string]*Asset)}
+	for i := range assets {
+		a := &assets[i]
+		a.Criticality = strings.ToLower(strings.TrimSpace(a.Criticality))
+		if _, ok := criticalityWeights[a.Criticality]; a.Criticality != "" && !ok {
+			return nil, fmt.Errorf("%s: record %d: unknown criticality %q (want low, medium, high or critical)", filename, i+1, a.Criticality)
+		}
+		if a.IP == "" && a.Hostname == "" {
+			return nil, fmt.Errorf("%s: record %d: needs an ip or a hostname", filename, i+1)
+		}
+		if a.IP != "" {
+			inv.byIP[canonicalIP(a.IP)] = a
+		}
+		if a.Hostname != "" {
+			inv.byName[strings.ToLower(strings.TrimSpace(a.Hostname))] = a
+		}
+		for _, t := range a.Tags {
+			if !containsString(inv.Tags, t) {
+				inv.Tags = append(inv.Tags, t)
+			}
+		}
+	}
+	sort.Strings(inv.Tags)
+	return inv, nil
+}
+
+// canonicalIP gives an address its textual form as nmap prints it, so
+// "2001:DB8::1" and "2001:db8:0::1" both match "2001:db8::1"
+func canonicalIP(s string) string {
+	s = strings.TrimSpace(s)
+	if ip, err := netip.ParseAddr(s); err == nil {
+		return ip.String()
+	}
+	return s
+}
+
+// parseAssetCSV maps the header row through csvColumns; unknown columns are ignored
+func parseAssetCSV(r io.Reader) ([]Asset, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("empty file")
+	}
+	columns := make([]string, len(rows[0]))
+	known := false
+	for i, h := range rows[0] {
+		key := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(h), " ", "_"))
+		columns[i] = csvColumns[key]
+		known = known || columns[i] != ""
+	}
+	if !known {
+		return nil, fmt.Errorf("header row has none of ip, hostname, owner, environment, criticality, tags")
+	}
+
+	var assets []Asset
+	for _, row := range rows[1:] {
+		var a Asset
+		for i, cell := range row {
+			if i >= len(columns) {
+				break
+			}
+			cell = strings.TrimSpace(cell)
+			switch columns[i] {
+			case "ip":
+				a.IP = cell
+			case "hostname":
+				a.Hostname = cell
+			case "owner":
+				a.Owner = cell
+			case "environment":
+				a.Environment = cell
+			case "criticality":
+				a.Criticality = cell
+			case "tags":
+				a.Tags = splitTags(cell)
+			}
+		}
+		if a.IP == "" && a.Hostname == "" && a.Owner == "" {
+			continue // blank line
+		}
+		assets = append(assets, a)
+	}
+	return assets, nil
+}
+
+// Lookup finds the record for a host, by any of its addresses first and
+// then by any of its hostnames
+func (inv *AssetInventory) Lookup(h Host) *Asset {
+	if inv == nil {
+		return nil
+	}
+	for _, a := range h.Addresses {
+		if asset, ok := inv.byIP[canonicalIP(a.Addr)]; ok {
+			return asset
+		}
+	}
+	for _, n := range h.Hostnames.Names {
+		if asset, ok := inv.byName[strings.ToLower(n.Name)]; ok {
+			return asset
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeAssets writes an inventory file and loads it
+func writeAssets(t *testing.T, name, body string) (*AssetInventory, error) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return loadAssetFile(file)
+}
+
+func TestParseAssetCSVHeaderAliases(t *testing.T) {
+	csv := "IP Address,FQDN,Owner,Env,Business Criticality,Tags,Rack\n" +
+		"10.0.0.1, web01.example.com ,Web team,prod,High,pci; external|web,R12\n" +
+		"\n" +
+		",db01,DBA,,,,\n"
+	got, err := parseAssetCSV(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Asset{
+		{IP: "10.0.0.1", Hostname: "web01.example.com", Owner: "Web team", Environment: "prod", Criticality: "High", Tags: []string{"pci", "external", "web"}},
+		{Hostname: "db01", Owner: "DBA"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	if _, err := parseAssetCSV(strings.NewReader("serial,rack\n1,2\n")); err == nil {
+		t.Error("header without known columns accepted")
+	}
+}
+
+func TestLoadAssetFileJSONTags(t *testing.T) {
+	inv, err := writeAssets(t, "assets.json", `[
+		{"ip": "10.0.0.1", "criticality": "Critical", "tags": ["pci", " pci ", "web"]},
+		{"hostname": "db01", "tags": "db;prod"}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	web := inv.Lookup(testHost("10.0.0.1", "up"))
+	if web == nil || web.Criticality != RiskCritical || !reflect.DeepEqual([]string(web.Tags), []string{"pci", "web"}) {
+		t.Errorf("list tags: got %+v", web)
+	}
+	db := Host{Hostnames: Hostnames{Names: []Hostname{{Name: "DB01"}}}}
+	if a := inv.Lookup(db); a == nil || !reflect.DeepEqual([]string(a.Tags), []string{"db", "prod"}) {
+		t.Errorf("string tags: got %+v", a)
+	}
+	if want := []string{"db", "pci", "prod", "web"}; !reflect.DeepEqual(inv.Tags, want) {
+		t.Errorf("inventory tags = %v, want %v", inv.Tags, want)
+	}
+
+	if _, err := writeAssets(t, "bad.json", `[{"ip": "10.0.0.1", "tags": 3}]`); err == nil {
+		t.Error("numeric tags accepted")
+	}
+}
+
+func TestLoadAssetFileErrors(t *testing.T) {
+	tests := []struct {
+		name, body string
+	}{
+		{"unknown criticality", "ip,criticality\n10.0.0.1,severe\n"},
+		{"no ip or hostname", `[{"owner": "ops"}]`},
+		{"empty csv", ""},
+		{"bad json", `[{"ip": }]`},
+	}
+	for _, tt := range tests {
+		if _, err := writeAssets(t, "assets", tt.body); err == nil {
+			t.Errorf("%s: loaded without error", tt.name)
+		}
+	}
+}
+
+func TestAssetLookup(t *testing.T) {
+	inv, err := writeAssets(t, "assets.csv", "ip,hostname,owner\n"+
+		"10.0.0.1,,by-ip\n"+
+		",web01,by-name\n"+
+		"2001:DB8:0::1,,by-ipv6\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	named := func(h Host, name string) Host {
+		h.Hostnames.Names = []Hostname{{Name: name}}
+		return h
+	}
+	tests := []struct {
+		name string
+		host Host
+		want string
+	}{
+		{"address", testHost("10.0.0.1", "up"), "by-ip"},
+		{"address before hostname", named(testHost("10.0.0.1", "up"), "web01"), "by-ip"},
+		{"hostname", named(testHost("10.0.0.2", "up"), "WEB01"), "by-name"},
+		{"ipv6 case and zero runs", testHost("2001:db8::1", "up"), "by-ipv6"},
+		{"no match", testHost("10.0.0.3", "up"), ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if a := inv.Lookup(tt.host); a != nil {
+			got = a.Owner
+		}
+		if got != tt.want {
+			t.Errorf("%s: owner %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	var none *AssetInventory
+	if none.Lookup(testHost("10.0.0.1", "up")) != nil {
+		t.Error("nil inventory returned an asset")
+	}
+}
+
+func TestScoreHostCriticality(t *testing.T) {
+	tests := []struct {
+		criticality string
+		ports       []Port
+		want        int
+	}{
+		{"", []Port{testPort(25, "smtp")}, 10},
+		{RiskLow, []Port{testPort(25, "smtp")}, 5},
+		{RiskMedium, []Port{testPort(25, "smtp")}, 10},
+		{RiskHigh, []Port{testPort(25, "smtp")}, 15},
+		{RiskCritical, []Port{testPort(25, "smtp")}, 20},
+		{RiskCritical, []Port{testPort(23, "telnet"), testPort(21, "ftp")}, 100},
+	}
+	for _, tt := range tests {
+		h := testHost("10.0.0.1", "up", tt.ports...)
+		if tt.criticality != "" {
+			h.Asset = &Asset{IP: "10.0.0.1", Criticality: tt.criticality}
+		}
+		scoreHost(&h, defaultRiskRules)
+		if h.Risk.Score != tt.want {
+			t.Errorf("criticality %q: score %d, want %d", tt.criticality, h.Risk.Score, tt.want)
+		}
+		if h.Risk.Criticality != tt.criticality {
+			t.Errorf("criticality %q: recorded %q", tt.criticality, h.Risk.Criticality)
+		}
+	}
+}
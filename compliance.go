@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ComplianceFile is the on-disk format for -compliance, YAML or JSON. Every
+// group maps hosts (CIDRs, addresses or hostname globs) to the ports that
+// must be open; optional ports may be open but are not required.
+//
+//	groups:
+//	  - name: DMZ web servers
+//	    hosts: [10.0.1.0/24, "web*.example.com"]
+//	    ports: [80/tcp, 443/tcp]
+//	  - name: Bastions
+//	    hosts: [10.0.0.10]
+//	    ports: [22/tcp]
+//	    optional: [443/tcp]
+type ComplianceFile struct {
+	Groups []ComplianceGroupSpec `yaml:"groups"`
+}
+
+type ComplianceGroupSpec struct {
+	Name     string   `yaml:"name"`
+	Hosts    []string `yaml:"hosts"`
+	Ports    []string `yaml:"ports"`
+	Optional []string `yaml:"optional"`
+}
+
+// ComplianceGroup is a compiled baseline group
+type ComplianceGroup struct {
+	Name     string
+	nets     []*net.IPNet
+	names    []string // lower-case hostname globs
+	Expected []PortSpec
+	Optional []PortSpec
+}
+
+// ComplianceBaseline is the loaded -compliance file
+type ComplianceBaseline struct {
+	Groups []ComplianceGroup
+}
+
+// loadComplianceFile reads and validates a baseline file
+func loadComplianceFile(filename string) (*ComplianceBaseline, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var cf ComplianceFile
+	if err := yaml.Unmarshal(b, &cf); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", filename, err)
+	}
+	if len(cf.Groups) == 0 {
+		return nil, fmt.Errorf("%s: no groups defined", filename)
+	}
+
+	baseline := &ComplianceBaseline{}
+	for i, spec := range cf.Groups {
+		name := spec.Name
+		if name == "" {
+			name = fmt.Sprintf("group %d", i+1)
+		}
+		g := ComplianceGroup{Name: name}
+		if len(spec.Hosts) == 0 {
+			return nil, fmt.Errorf("%s: no hosts", name)
+		}
+		for _, h := range spec.Hosts {
+			h = strings.TrimSpace(h)
+			if n, err := parseNetwork(h); err == nil {
+				g.nets = append(g.nets, n)
+				continue
+			}
+			if _, err := path.Match(h, ""); err != nil {
+				return nil, fmt.Errorf("%s: host %q: %w", name, h, err)
+			}
+			g.names = append(g.names, strings.ToLower(h))
+		}
+		if g.Expected, err = parsePortSpecs(strings.Join(spec.Ports, ",")); err != nil {
+			return nil, fmt.Errorf("%s: ports: %w", name, err)
+		}
+		if g.Optional, err = parsePortSpecs(strings.Join(spec.Optional, ",")); err != nil {
+			return nil, fmt.Errorf("%s: optional: %w", name, err)
+		}
+		baseline.Groups = append(baseline.Groups, g)
+	}
+	return baseline, nil
+}
+
+// parseNetwork accepts a CIDR or a bare address, which becomes a single-host network
+func parseNetwork(s string) (*net.IPNet, error) {
+	if _, n, err := net.ParseCIDR(s); err == nil {
+		return n, nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("not an address: %q", s)
+	}
+	bits := 128
+	if ip.To4() != nil {
+		ip, bits = ip.To4(), 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// Covers reports whether any of the host's addresses or names belong to the group
+func (g ComplianceGroup) Covers(h Host) bool {
+	for _, a := range h.Addresses {
+		ip := net.ParseIP(a.Addr)
+		if ip == nil {
+			continue
+		}
+		for _, n := range g.nets {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+	}
+	for _, hn := range h.Hostnames.Names {
+		for _, pattern := range g.names {
+			if ok, _ := path.Match(pattern, strings.ToLower(hn.Name)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HostCompliance is the baseline result for one host
+type HostCompliance struct {
+	Location   ReportFinding
+	Groups     []string
+	Unexpected []Port     // open but allowed by no group
+	Missing    []PortSpec // expected by a group but not open
+}
+
+// Passed reports whether the host exposes exactly what its groups allow
+func (c HostCompliance) Passed() bool {
+	return len(c.Unexpected) == 0 && len(c.Missing) == 0
+}
+
+// Evaluate checks the host against every group covering it; hosts in several
+// groups may expose the union of their ports. Nil when no group covers the
+// host or it is not up.
+func (b *ComplianceBaseline) Evaluate(h Host) *HostCompliance {
+	if b == nil || h.Status.State != "up" {
+		return nil
+	}
+	var groups []ComplianceGroup
+	for _, g := range b.Groups {
+		if g.Covers(h) {
+			groups = append(groups, g)
+		}
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+
+	c := &HostCompliance{Location: ReportFinding{Addr: h.PrimaryAddr(), Hostname: h.PrimaryName()}}
+	var expected, allowed []PortSpec
+	for _, g := range groups {
+		c.Groups = append(c.Groups, g.Name)
+		expected = append(expected, g.Expected...)
+		allowed = append(allowed, g.Expected...)
+		allowed = append(allowed, g.Optional...)
+	}
+
+	open := h.OpenPorts()
+	for _, p := range open {
+		ok := false
+		for _, spec := range allowed {
+			if spec.Matches(p) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			c.Unexpected = append(c.Unexpected, p)
+		}
+	}
+	seen := make(map[PortSpec]bool)
+	for _, spec := range expected {
+		if seen[spec] {
+			continue
+		}
+		seen[spec] = true
+		found := false
+		for _, p := range open {
+			if spec.Matches(p) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			c.Missing = append(c.Missing, spec)
+		}
+	}
+	return c
+}
+
+// ComplianceReport collects the per-host results for the report footer
+type ComplianceReport struct {
+	Hosts     []HostCompliance // every covered host, in scan order
+	Uncovered int              // hosts up but in no group
+}
+
+// Add records one host's result
+func (r *ComplianceReport) Add(c *HostCompliance) {
+	if c == nil {
+		r.Uncovered++
+		return
+	}
+	r.Hosts = append(r.Hosts, *c)
+}
+
+// Passed reports whether every covered host passed. A run that checked no
+// host fails, since an empty scan or a baseline matching nothing proves
+// nothing.
+func (r *ComplianceReport) Passed() bool {
+	return len(r.Hosts) > 0 && len(r.Failures()) == 0
+}
+
+// Failures lists the hosts with violations
+func (r *ComplianceReport) Failures() []HostCompliance {
+	var out []HostCompliance
+	for _, c := range r.Hosts {
+		if !c.Passed() {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// UnexpectedCount is the number of open ports no group allows
+func (r *ComplianceReport) UnexpectedCount() int {
+	n := 0
+	for _, c := range r.Hosts {
+		n += len(c.Unexpected)
+	}
+	return n
+}
+
+// MissingCount is the number of expected ports found closed
+func (r *ComplianceReport) MissingCount() int {
+	n := 0
+	for _, c := range r.Hosts {
+		n += len(c.Missing)
+	}
+	return n
+}
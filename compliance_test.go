@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestComplianceEvaluate(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "baseline.yaml")
+	os.WriteFile(file, []byte(`groups:
+  - name: web
+    hosts: [10.0.1.0/24, "web*.example.com"]
+    ports: [80/tcp, 443/tcp]
+  - name: bastion
+    hosts: [10.0.0.10]
+    ports: [22/tcp]
+    optional: [443]
+`), 0o600)
+	baseline, err := loadComplianceFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	named := testHost("192.168.5.5", "up", testPort(80, "http"), testPort(443, "https"))
+	named.Hostnames.Names = []Hostname{{Name: "WEB01.example.com"}}
+	tests := []struct {
+		name       string
+		host       Host
+		covered    bool
+		unexpected int
+		missing    int
+	}{
+		{"exact match", testHost("10.0.1.5", "up", testPort(80, "http"), testPort(443, "https")), true, 0, 0},
+		{"unexpected and missing", testHost("10.0.1.6", "up", testPort(80, "http"), testPort(3306, "mysql")), true, 1, 1},
+		{"optional port", testHost("10.0.0.10", "up", testPort(22, "ssh"), testPort(443, "https")), true, 0, 0},
+		{"hostname glob", named, true, 0, 0},
+		{"not covered", testHost("172.16.0.1", "up", testPort(22, "ssh")), false, 0, 0},
+		{"down host", testHost("10.0.1.7", "down"), false, 0, 0},
+	}
+	for _, tt := range tests {
+		c := baseline.Evaluate(tt.host)
+		if (c != nil) != tt.covered {
+			t.Errorf("%s: covered = %v, want %v", tt.name, c != nil, tt.covered)
+			continue
+		}
+		if c != nil && (len(c.Unexpected) != tt.unexpected || len(c.Missing) != tt.missing) {
+			t.Errorf("%s: unexpected %d, missing %d; want %d, %d", tt.name, len(c.Unexpected), len(c.Missing), tt.unexpected, tt.missing)
+		}
+	}
+}
+
+func TestComplianceReportPassed(t *testing.T) {
+	pass := &HostCompliance{Groups: []string{"web"}}
+	fail := &HostCompliance{Groups: []string{"web"}, Missing: []PortSpec{{Port: 443, Protocol: "tcp"}}}
+	tests := []struct {
+		name    string
+		results []*HostCompliance
+		want    bool
+	}{
+		{"no hosts", nil, false},
+		{"only uncovered hosts", []*HostCompliance{nil, nil}, false},
+		{"all passing", []*HostCompliance{pass, nil}, true},
+		{"one failing", []*HostCompliance{pass, fail}, false},
+	}
+	for _, tt := range tests {
+		var r ComplianceReport
+		for _, c := range tt.results {
+			r.Add(c)
+		}
+		if got := r.Passed(); got != tt.want {
+			t.Errorf("%s: Passed = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CVE is one vulnerability reported for a port by an NSE script
+type CVE struct {
+	ID      string  `json:"id"`
+	CVSS    float64 `json:"cvss,omitempty"`
+	Exploit bool    `json:"exploit,omitempty"`
+	Source  string  `json:"source"` // script ID, e.g. vulners, or "nvd"
+
+	// Potential marks CVEs inferred from the service CPE and version rather
+	// than reported by a script
+	Potential bool `json:"potential,omitempty"`
+}
+
+// Severity maps the CVSS score onto the report's risk levels
+func (c CVE) Severity() string {
+	switch {
+	case c.CVSS >= 9:
+		return RiskCritical
+	case c.CVSS >= 7:
+		return RiskHigh
+	case c.CVSS >= 4:
+		return RiskMedium
+	case c.CVSS > 0:
+		return RiskLow
+	default:
+		return RiskInfo
+	}
+}
+
+// Score formats the CVSS score for display, "-" when unknown
+func (c CVE) Score() string {
+	if c.CVSS == 0 {
+		return "-"
+	}
+	return strconv.FormatFloat(c.CVSS, 'f', 1, 64)
+}
+
+// URL points at the NVD entry
+func (c CVE) URL() string {
+	return "https://nvd.nist.gov/vuln/detail/" + c.ID
+}
+
+var cvePattern = regexp.MustCompile(`CVE-\d{4}-\d{4,}`)
+
+// extractCVEs collects the CVEs reported by a port's or a host's scripts.
+// Structured <table> output is preferred; the text output is used when a
+// script has none (older nmap, vulscan).
+func extractCVEs(scripts []Script) []CVE {
+	found := make(map[string]*CVE)
+	var order []string
+	add := func(c CVE) {
+		if prev, ok := found[c.ID]; ok {
+			if c.CVSS > prev.CVSS {
+				prev.CVSS = c.CVSS
+			}
+			prev.Exploit = prev.Exploit || c.Exploit
+			return
+		}
+		found[c.ID] = &c
+		order = append(order, c.ID)
+	}
+
+	for _, s := range scripts {
+		if len(s.Tables) > 0 {
+			for _, t := range s.Tables {
+				walkCVETable(s.ID, t, add)
+			}
+			continue
+		}
+		cvesFromText(s, add)
+	}
+
+	out := make([]CVE, 0, len(order))
+	for _, id := range order {
+		out = append(out, *found[id])
+	}
+	sortCVEs(out)
+	return out
+}
+
+// walkCVETable handles the two structured layouts in use:
+//
+//	vulners: <table key="cpe:..."><table><elem key="id">CVE-..</elem><elem key="cvss">7.5</elem>
+//	         <elem key="is_exploit">false</elem></table></table>
+//	vuln:    <table key="CVE-2017-0143"><elem key="state">VULNERABLE</elem>
+//	         <table key="ids"><elem>CVE:CVE-2017-0143</elem></table>
+//	         <table key="scores"><elem key="CVSSv2">9.3</elem></table></table>
+func walkCVETable(source string, t ScriptTable, add func(CVE)) {
+	var ids []string
+	var cvss float64
+	exploit, notVulnerable := false, false
+
+	for _, e := range t.Elems {
+		key := strings.ToLower(e.Key)
+		switch {
+		case key == "id":
+			ids = append(ids, cvePattern.FindAllString(e.Value, -1)...)
+		case strings.HasPrefix(key, "cvss"):
+			cvss = parseCVSS(e.Value)
+		case key == "is_exploit":
+			exploit = e.Value == "true"
+		case key == "state":
+			state := strings.ToUpper(e.Value)
+			notVulnerable = strings.Contains(state, "NOT VULNERABLE")
+			exploit = exploit || strings.Contains(state, "EXPLOITABLE")
+		}
+	}
+	for _, sub := range t.Tables {
+		switch strings.ToLower(sub.Key) {
+		case "ids":
+			for _, e := range sub.Elems {
+				ids = append(ids, cvePattern.FindAllString(e.Value, -1)...)
+			}
+		case "scores":
+			for _, e := range sub.Elems {
+				if v := parseCVSS(e.Value); v > cvss {
+					cvss = v
+				}
+			}
+		default:
+			walkCVETable(source, sub, add)
+		}
+	}
+	if len(ids) == 0 {
+		ids = cvePattern.FindAllString(t.Key, -1)
+	}
+	if notVulnerable {
+		return
+	}
+	for _, id := range ids {
+		add(CVE{ID: id, CVSS: cvss, Exploit: exploit, Source: source})
+	}
+}
+
+// vulnBlockHeader starts a block in the vuln library's text layout, e.g.
+// "VULNERABLE:", "NOT VULNERABLE:" or "VULNERABLE (Exploitable):"
+var vulnBlockHeader = regexp.MustCompile(`(?i)^\s*(not |likely )?vulnerable( \([^)]*\))?:\s*$`)
+
+// cvesFromText scans plain script output line by line. vulners lines look
+// like "CVE-2021-41773  7.5  https://vulners.com/...  *EXPLOIT*", so the
+// score is the field that follows the ID; vulscan lines carry no score.
+// In the vuln layout a "NOT VULNERABLE" state drops every CVE of its block,
+// including the "IDs:" line.
+func cvesFromText(s Script, add func(CVE)) {
+	var block []CVE
+	notVulnerable := false
+	flush := func() {
+		if !notVulnerable {
+			for _, c := range block {
+				add(c)
+			}
+		}
+		block, notVulnerable = nil, false
+	}
+	for _, line := range strings.Split(s.Output, "\n") {
+		if vulnBlockHeader.MatchString(line) {
+			flush()
+		}
+		if strings.Contains(strings.ToUpper(line), "NOT VULNERABLE") {
+			notVulnerable = true
+			continue
+		}
+		exploit := strings.Contains(line, "*EXPLOIT*")
+		fields := strings.Fields(line)
+		for i, f := range fields {
+			for _, id := range cvePattern.FindAllString(f, -1) {
+				c := CVE{ID: id, Exploit: exploit, Source: s.ID}
+				if i+1 < len(fields) {
+					c.CVSS = parseCVSS(fields[i+1])
+				}
+				block = append(block, c)
+			}
+		}
+	}
+	flush()
+}
+
+// parseCVSS accepts "7.5" or "9.3 (HIGH)"; anything outside 0-10 is ignored
+func parseCVSS(s string) float64 {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexAny(s, " ("); i > 0 {
+		s = s[:i]
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 || v > 10 {
+		return 0
+	}
+	return v
+}
+
+// sortCVEs orders by CVSS, then exploit availability, then ID
+func sortCVEs(cves []CVE) {
+	sort.SliceStable(cves, func(i, j int) bool {
+		a, b := cves[i], cves[j]
+		if a.CVSS != b.CVSS {
+			return a.CVSS > b.CVSS
+		}
+		if a.Exploit != b.Exploit {
+			return a.Exploit
+		}
+		return a.ID > b.ID
+	})
+}
+
+// mergeCVEs adds extra CVEs not already on the list and re-sorts it
+func mergeCVEs(cves, extra []CVE) []CVE {
+	have := make(map[string]bool, len(cves))
+	for _, c := range cves {
+		have[c.ID] = true
+	}
+	for _, c := range extra {
+		if !have[c.ID] {
+			have[c.ID] = true
+			cves = append(cves, c)
+		}
+	}
+	sortCVEs(cves)
+	return cves
+}
+
+// maxCVSS is the highest score among the CVEs reported by scripts.
+// Potential (version-matched) CVEs are left to maxPotentialCVSS.
+func maxCVSS(cves []CVE) float64 {
+	max := 0.0
+	for _, c := range cves {
+		if !c.Potential && c.CVSS > max {
+			max = c.CVSS
+		}
+	}
+	return max
+}
+
+// maxPotentialCVSS is the highest score among the potential CVEs
+func maxPotentialCVSS(cves []CVE) float64 {
+	max := 0.0
+	for _, c := range cves {
+		if c.Potential && c.CVSS > max {
+			max = c.CVSS
+		}
+	}
+	return max
+}
+
+func hasExploitableCVE(cves []CVE) bool {
+	for _, c := range cves {
+		if c.Exploit && !c.Potential {
+			return true
+		}
+	}
+	return false
+}
+
+// CVESummary is one CVE across every port it was reported on
+type CVESummary struct {
+	CVE
+	Hosts    int             `json:"hosts"`
+	Affected []ReportFinding `json:"affected"`
+}
+
+// hostCVEs places each host and port CVE on its host, reusing ReportFinding
+// for the location so the report can link back to the host card
+func hostCVEs(h Host) []CVESummary {
+	var out []CVESummary
+	for _, c := range h.CVEs {
+		out = append(out, CVESummary{CVE: c, Hosts: 1, Affected: []ReportFinding{{
+			Addr:     h.PrimaryAddr(),
+			Hostname: h.PrimaryName(),
+		}}})
+	}
+	for _, p := range h.Ports.Ports {
+		for _, c := range p.CVEs {
+			out = append(out, CVESummary{CVE: c, Hosts: 1, Affected: []ReportFinding{{
+				Addr:     h.PrimaryAddr(),
+				Hostname: h.PrimaryName(),
+				Port:     p.PortId,
+				Protocol: p.Protocol,
+			}}})
+		}
+	}
+	return out
+}
+
+// topCVEs merges per-host CVEs and keeps the n most severe
+func topCVEs(occurrences []CVESummary, n int) []CVESummary {
+	index := make(map[string]int)
+	var merged []CVESummary
+	hosts := make(map[string]map[string]bool)
+	for _, o := range occurrences {
+		i, ok := index[o.ID]
+		if !ok {
+			i = len(merged)
+			index[o.ID] = i
+			merged = append(merged, CVESummary{CVE: o.CVE})
+			hosts[o.ID] = make(map[string]bool)
+		}
+		m := &merged[i]
+		if o.CVSS > m.CVSS {
+			m.CVSS = o.CVSS
+		}
+		m.Exploit = m.Exploit || o.Exploit
+		m.Potential = m.Potential && o.Potential
+		for _, a := range o.Affected {
+			m.Affected = append(m.Affected, a)
+			if !hosts[o.ID][a.Addr] {
+				hosts[o.ID][a.Addr] = true
+				m.Hosts++
+			}
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		a, b := merged[i], merged[j]
+		if a.CVSS != b.CVSS {
+			return a.CVSS > b.CVSS
+		}
+		if a.Exploit != b.Exploit {
+			return a.Exploit
+		}
+		if a.Hosts != b.Hosts {
+			return a.Hosts > b.Hosts
+		}
+		return a.ID > b.ID
+	})
+	if n > 0 && len(merged) > n {
+		merged = merged[:n]
+	}
+	return merged
+}
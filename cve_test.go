@@ -0,0 +1,142 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCVSS(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"7.5", 7.5},
+		{" 9.3 (HIGH)", 9.3},
+		{"10.0", 10},
+		{"11", 0},
+		{"-1", 0},
+		{"n/a", 0},
+	}
+	for _, tt := range tests {
+		if got := parseCVSS(tt.in); got != tt.want {
+			t.Errorf("parseCVSS(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExtractCVEs(t *testing.T) {
+	tests := []struct {
+		name    string
+		scripts []Script
+		want    []CVE
+	}{
+		{
+			name: "vulners text",
+			scripts: []Script{{ID: "vulners", Output: "\n  cpe:/a:apache:http_server:2.4.49:\n" +
+				"    CVE-2021-41773  7.5  https://vulners.com/cve/CVE-2021-41773  *EXPLOIT*\n" +
+				"    CVE-2021-42013  9.8  https://vulners.com/cve/CVE-2021-42013\n"}},
+			want: []CVE{
+				{ID: "CVE-2021-42013", CVSS: 9.8, Source: "vulners"},
+				{ID: "CVE-2021-41773", CVSS: 7.5, Exploit: true, Source: "vulners"},
+			},
+		},
+		{
+			name: "vulners table",
+			scripts: []Script{{ID: "vulners", Tables: []ScriptTable{{Key: "cpe:/a:openbsd:openssh:7.4", Tables: []ScriptTable{{Elems: []ScriptElem{
+				{Key: "id", Value: "CVE-2023-38408"}, {Key: "cvss", Value: "9.8"}, {Key: "is_exploit", Value: "true"},
+			}}}}}}},
+			want: []CVE{{ID: "CVE-2023-38408", CVSS: 9.8, Exploit: true, Source: "vulners"}},
+		},
+		{
+			name: "vuln table",
+			scripts: []Script{{ID: "smb-vuln-ms17-010", Tables: []ScriptTable{{Key: "CVE-2017-0143",
+				Elems: []ScriptElem{{Key: "state", Value: "VULNERABLE"}},
+				Tables: []ScriptTable{
+					{Key: "ids", Elems: []ScriptElem{{Value: "CVE:CVE-2017-0143"}}},
+					{Key: "scores", Elems: []ScriptElem{{Key: "CVSSv2", Value: "9.3"}}},
+				}}}}},
+			want: []CVE{{ID: "CVE-2017-0143", CVSS: 9.3, Source: "smb-vuln-ms17-010"}},
+		},
+		{
+			name: "not vulnerable",
+			scripts: []Script{{ID: "smb-vuln-ms10-054", Tables: []ScriptTable{{Key: "CVE-2010-2550",
+				Elems: []ScriptElem{{Key: "state", Value: "NOT VULNERABLE"}}}}}},
+			want: []CVE{},
+		},
+		{
+			name: "elems without tables use the text",
+			scripts: []Script{{ID: "vulners", Elems: []ScriptElem{{Key: "error", Value: "partial"}},
+				Output: "CVE-2023-38408  9.8  https://vulners.com/cve/CVE-2023-38408"}},
+			want: []CVE{{ID: "CVE-2023-38408", CVSS: 9.8, Source: "vulners"}},
+		},
+		{
+			name: "vuln text skips not vulnerable blocks",
+			scripts: []Script{{ID: "smb-vuln-ms17-010", Output: "\n  NOT VULNERABLE:\n  Remote Code Execution vulnerability in Microsoft SMBv1 servers (ms17-010)\n" +
+				"    State: NOT VULNERABLE\n    IDs:  CVE:CVE-2017-0143\n    Risk factor: HIGH\n" +
+				"  VULNERABLE:\n  Some other vulnerability\n    State: VULNERABLE\n    IDs:  CVE:CVE-2020-0796\n"}},
+			want: []CVE{{ID: "CVE-2020-0796", Source: "smb-vuln-ms17-010"}},
+		},
+		{
+			name:    "vuln text state after ids",
+			scripts: []Script{{ID: "http-vuln-cve2017-5638", Output: "  VULNERABLE:\n    IDs:  CVE:CVE-2017-5638\n    State: NOT VULNERABLE\n"}},
+			want:    []CVE{},
+		},
+		{
+			name: "duplicate keeps highest score",
+			scripts: []Script{
+				{ID: "vulscan", Output: "[CVE-2021-41773] Apache path traversal"},
+				{ID: "vulners", Output: "CVE-2021-41773 7.5 https://vulners.com/cve/CVE-2021-41773"},
+			},
+			want: []CVE{{ID: "CVE-2021-41773", CVSS: 7.5, Source: "vulscan"}},
+		},
+	}
+	for _, tt := range tests {
+		if got := extractCVEs(tt.scripts); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCVERulesSkipPotential(t *testing.T) {
+	p := testPort(22, "ssh")
+	p.CVEs = []CVE{
+		{ID: "CVE-2023-38408", CVSS: 9.8, Exploit: true, Source: "nvd", Potential: true},
+		{ID: "CVE-2016-20012", CVSS: 5.3, Source: "vulners"},
+	}
+	if got := maxCVSS(p.CVEs); got != 5.3 {
+		t.Errorf("maxCVSS = %v, want 5.3", got)
+	}
+	if hasExploitableCVE(p.CVEs) {
+		t.Error("hasExploitableCVE counted a potential CVE")
+	}
+	if got := maxPotentialCVSS(p.CVEs); got != 9.8 {
+		t.Errorf("maxPotentialCVSS = %v, want 9.8", got)
+	}
+}
+
+func TestHostScriptCVEs(t *testing.T) {
+	h := testHost("10.0.0.5", "up", testPort(445, "microsoft-ds"))
+	h.HostScripts = []Script{{ID: "smb-vuln-ms17-010", Tables: []ScriptTable{{Key: "CVE-2017-0143",
+		Elems: []ScriptElem{{Key: "state", Value: "VULNERABLE"}},
+		Tables: []ScriptTable{
+			{Key: "ids", Elems: []ScriptElem{{Value: "CVE:CVE-2017-0143"}}},
+			{Key: "scores", Elems: []ScriptElem{{Key: "CVSSv2", Value: "9.3"}}},
+		}}}}}
+	h.CVEs = extractCVEs(h.HostScripts)
+
+	occurrences := hostCVEs(h)
+	if len(occurrences) != 1 || occurrences[0].ID != "CVE-2017-0143" {
+		t.Fatalf("hostCVEs = %+v", occurrences)
+	}
+	if a := occurrences[0].Affected[0]; a.Addr != "10.0.0.5" || a.Port != 0 || a.Target() != "10.0.0.5" {
+		t.Errorf("affected = %+v, want the host without a port", a)
+	}
+
+	found := false
+	for _, f := range hostRuleFindings(h, defaultRiskRules) {
+		found = found || f.RuleID == "cve-critical"
+	}
+	if !found {
+		t.Error("cve-critical not raised for a host script CVE")
+	}
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// ScanDiff is the machine-readable result of comparing a baseline scan with a
+// current scan of the same scope
+type ScanDiff struct {
+	Baseline       DiffRun         `json:"baseline"`
+	Current        DiffRun         `json:"current"`
+	Summary        DiffSummary     `json:"summary"`
+	HostsAdded     []DiffHost      `json:"hosts_added"`
+	HostsRemoved   []DiffHost      `json:"hosts_removed"`
+	PortsOpened    []PortChange    `json:"ports_opened"`
+	PortsClosed    []PortChange    `json:"ports_closed"`
+	ServiceChanges []ServiceChange `json:"service_changes"`
+}
+
+type DiffRun struct {
+	Source   string `json:"source,omitempty"`
+	Args     string `json:"args,omitempty"`
+	StartStr string `json:"start,omitempty"`
+	Hosts    int    `json:"hosts_up"`
+}
+
+type DiffSummary struct {
+	HostsAdded     int `json:"hosts_added"`
+	HostsRemoved   int `json:"hosts_removed"`
+	PortsOpened    int `json:"ports_opened"`
+	PortsClosed    int `json:"ports_closed"`
+	ServiceChanges int `json:"service_changes"`
+}
+
+// Changed reports whether the two scans differ at all
+func (s DiffSummary) Changed() bool {
+	return s.HostsAdded+s.HostsRemoved+s.PortsOpened+s.PortsClosed+s.ServiceChanges > 0
+}
+
+type DiffHost struct {
+	Addr      string        `json:"addr"`
+	Hostname  string        `json:"hostname,omitempty"`
+	OpenPorts []ServiceInfo `json:"open_ports,omitempty"`
+}
+
+// ServiceInfo is the comparable part of a port
+type ServiceInfo struct {
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	Service  string `json:"service,omitempty"`
+	Product  string `json:"product,omitempty"`
+	Version  string `json:"version,omitempty"`
+}
+
+func serviceInfo(p Port) ServiceInfo {
+	return ServiceInfo{
+		Port:     p.PortId,
+		Protocol: p.Protocol,
+		Service:  p.Service.Name,
+		Product:  p.Service.Product,
+		Version:  p.Service.Version,
+	}
+}
+
+// Label renders "product version" or "-"
+func (s ServiceInfo) Label() string {
+	label := s.Product
+	if s.Version != "" {
+		label += " " + s.Version
+	}
+	if label == "" {
+		return "-"
+	}
+	return label
+}
+
+type PortChange struct {
+	Addr     string `json:"addr"`
+	Hostname string `json:"hostname,omitempty"`
+	ServiceInfo
+}
+
+type ServiceChange struct {
+	Addr     string      `json:"addr"`
+	Hostname string      `json:"hostname,omitempty"`
+	Port     int         `json:"port"`
+	Protocol string      `json:"protocol"`
+	Before   ServiceInfo `json:"before"`
+	After    ServiceInfo `json:"after"`
+}
+
+// upHostsByAddr indexes the hosts that were up by primary address
+func upHostsByAddr(hosts []Host) map[string]Host {
+	m := make(map[string]Host)
+	for _, h := range hosts {
+		if h.Status.State == "up" && h.PrimaryAddr() != "" {
+			m[h.PrimaryAddr()] = h
+		}
+	}
+	return m
+}
+
+func portKey(p Port) string {
+	return fmt.Sprintf("%d/%s", p.PortId, p.Protocol)
+}
+
+func openPortsByKey(h Host) map[string]Port {
+	m := make(map[string]Port)
+	for _, p := range h.OpenPorts() {
+		m[portKey(p)] = p
+	}
+	return m
+}
+
+func diffHost(h Host) DiffHost {
+	dh := DiffHost{Addr: h.PrimaryAddr(), Hostname: h.PrimaryName()}
+	for _, p := range h.OpenPorts() {
+		dh.OpenPorts = append(dh.OpenPorts, serviceInfo(p))
+	}
+	return dh
+}
+
+// compareScans builds the diff between a baseline and a current scan. Hosts
+// are matched on their primary address and ports on number and protocol.
+func compareScans(baseInfo NmapRunInfo, baseHosts []Host, curInfo NmapRunInfo, curHosts []Host) ScanDiff {
+	before := upHostsByAddr(baseHosts)
+	after := upHostsByAddr(curHosts)
+
+	d := ScanDiff{
+		Baseline: DiffRun{Args: baseInfo.Args, StartStr: baseInfo.StartStr, Hosts: len(before)},
+		Current:  DiffRun{Args: curInfo.Args, StartStr: curInfo.StartStr, Hosts: len(after)},
+		// non-nil so the JSON form always carries arrays
+		HostsAdded:     []DiffHost{},
+		HostsRemoved:   []DiffHost{},
+		PortsOpened:    []PortChange{},
+		PortsClosed:    []PortChange{},
+		ServiceChanges: []ServiceChange{},
+	}
+
+	for addr, h := range after {
+		old, seen := before[addr]
+		if !seen {
+			d.HostsAdded = append(d.HostsAdded, diffHost(h))
+			continue
+		}
+		oldPorts := openPortsByKey(old)
+		newPorts := openPortsByKey(h)
+		for key, p := range newPorts {
+			prev, wasOpen := oldPorts[key]
+			if !wasOpen {
+				d.PortsOpened = append(d.PortsOpened, PortChange{Addr: addr, Hostname: h.PrimaryName(), ServiceInfo: serviceInfo(p)})
+				continue
+			}
+			was, now := serviceInfo(prev), serviceInfo(p)
+			if was != now {
+				d.ServiceChanges = append(d.ServiceChanges, ServiceChange{
+					Addr: addr, Hostname: h.PrimaryName(), Port: p.PortId, Protocol: p.Protocol,
+					Before: was, After: now,
+				})
+			}
+		}
+		for key, p := range oldPorts {
+			if _, stillOpen := newPorts[key]; !stillOpen {
+				d.PortsClosed = append(d.PortsClosed, PortChange{Addr: addr, Hostname: h.PrimaryName(), ServiceInfo: serviceInfo(p)})
+			}
+		}
+	}
+	for addr, h := range before {
+		if _, seen := after[addr]; !seen {
+			d.HostsRemoved = append(d.HostsRemoved, diffHost(h))
+		}
+	}
+
+	sort.Slice(d.HostsAdded, func(i, j int) bool { return compareAddrs(d.HostsAdded[i].Addr, d.HostsAdded[j].Addr) < 0 })
+	sort.Slice(d.HostsRemoved, func(i, j int) bool { return compareAddrs(d.HostsRemoved[i].Addr, d.HostsRemoved[j].Addr) < 0 })
+	sortPortChanges(d.PortsOpened)
+	sortPortChanges(d.PortsClosed)
+	sort.Slice(d.ServiceChanges, func(i, j int) bool {
+		a, b := d.ServiceChanges[i], d.ServiceChanges[j]
+		if c := compareAddrs(a.Addr, b.Addr); c != 0 {
+			return c < 0
+		}
+		return a.Port < b.Port
+	})
+
+	d.Summary = DiffSummary{
+		HostsAdded:     len(d.HostsAdded),
+		HostsRemoved:   len(d.HostsRemoved),
+		PortsOpened:    len(d.PortsOpened),
+		PortsClosed:    len(d.PortsClosed),
+		ServiceChanges: len(d.ServiceChanges),
+	}
+	return d
+}
+
+func sortPortChanges(changes []PortChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		if c := compareAddrs(changes[i].Addr, changes[j].Addr); c != 0 {
+			return c < 0
+		}
+		if changes[i].Port != changes[j].Port {
+			return changes[i].Port < changes[j].Port
+		}
+		return changes[i].Protocol < changes[j].Protocol
+	})
+}
+
+// DiffTemplateData is the context for the embedded diff template
+type DiffTemplateData struct {
+	Diff      ScanDiff
+	CSS       template.CSS
+	Generated time.Time
+}
+
+// runCompare loads both scans, diffs them and writes HTML or JSON
+func runCompare(baselinePath string, in io.Reader, currentPath, outPath, format, css string) error {
+	if format != "html" && format != "json" {
+		return fmt.Errorf("compare mode supports -format html or json, not %q", format)
+	}
+	bf, err := os.Open(baselinePath)
+	if err != nil {
+		return fmt.Errorf("open baseline: %w", err)
+	}
+	defer bf.Close()
+	baseInfo, baseHosts, err := loadScan(bf)
+	if err != nil {
+		return fmt.Errorf("baseline: %w", err)
+	}
+	curInfo, curHosts, err := loadScan(in)
+	if err != nil {
+		return fmt.Errorf("current: %w", err)
+	}
+
+	diff := compareScans(baseInfo, baseHosts, curInfo, curHosts)
+	diff.Baseline.Source = baselinePath
+	diff.Current.Source = currentPath
+
+	out, err := createOutput(outPath)
+	if err != nil {
+		return fmt.Errorf("create output: %w", err)
+	}
+	defer out.Close()
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(diff)
+	case "html":
+		tpl, perr := template.New("diff").Parse(diffTemplate)
+		if perr != nil {
+			return fmt.Errorf("parse diff template: %w", perr)
+		}
+		err = tpl.Execute(out, DiffTemplateData{Diff: diff, CSS: template.CSS(css), Generated: time.Now()})
+	}
+	if err != nil {
+		return fmt.Errorf("write diff: %w", err)
+	}
+	return out.Close()
+}
+
+// Embedded diff template, styled by the same CSS as the main report
+const diffTemplate = `<!doctype html>
+<html lang="en">
+<head>
+  <meta charset="utf-8"/>
+  <meta name="viewport" content="width=device-width,initial-scale=1"/>
+  <title>Nmap Scan Comparison</title>
+  <style>{{.CSS}}</style>
+</head>
+<body>
+  <header class="topbar">
+    <div class="container">
+      <div class="brand">
+        <svg class="logo" viewBox="0 0 24 24" aria-hidden="true">
+          <path d="M12 2L2 7l10 5 10-5-10-5zM2 17l10 5 10-5M2 12l10 5 10-5" stroke="currentColor" stroke-width="1.5" stroke-linecap="round" stroke-linejoin="round" fill="none"/>
+        </svg>
+        <div>
+          <h1>Scan Comparison</h1>
+          <p class="muted">Baseline: {{or .Diff.Baseline.StartStr .Diff.Baseline.Source}} → Current: {{or .Diff.Current.StartStr .Diff.Current.Source}} • Generated: {{.Generated.Format "Jan 2, 2006 15:04"}}</p>
+        </div>
+      </div>
+    </div>
+  </header>
+
+  <main class="container">
+    <div class="stats-grid">
+      <div class="stat-card"><div class="stat-number">{{.Diff.Summary.HostsAdded}}</div><div class="stat-label">Hosts Added</div></div>
+      <div class="stat-card"><div class="stat-number">{{.Diff.Summary.HostsRemoved}}</div><div class="stat-label">Hosts Removed</div></div>
+      <div class="stat-card"><div class="stat-number">{{.Diff.Summary.PortsOpened}}</div><div class="stat-label">Ports Opened</div></div>
+      <div class="stat-card"><div class="stat-number">{{.Diff.Summary.PortsClosed}}</div><div class="stat-label">Ports Closed</div></div>
+      <div class="stat-card"><div class="stat-number">{{.Diff.Summary.ServiceChanges}}</div><div class="stat-label">Service Changes</div></div>
+    </div>
+
+    <section class="summary">
+      <div>
+        <strong>Baseline:</strong> <code style="font-size:12px;color:var(--muted);">{{.Diff.Baseline.Args}}</code> <span class="pill">{{.Diff.Baseline.Hosts}} hosts up</span><br/>
+        <strong>Current:</strong> <code style="font-size:12px;color:var(--muted);">{{.Diff.Current.Args}}</code> <span class="pill">{{.Diff.Current.Hosts}} hosts up</span>
+      </div>
+    </section>
+
+    {{if not .Diff.Summary.Changed}}
+    <p class="text-center muted">No changes between the two scans.</p>
+    {{end}}
+
+    {{if .Diff.HostsAdded}}
+    <h2>🆕 Hosts Added</h2>
+    <div class="hosts-grid">
+      {{range .Diff.HostsAdded}}
+      <article class="host-card">
+        <div class="host-name"><strong class="ip">{{.Addr}}</strong>{{if .Hostname}}<span class="hostname">{{.Hostname}}</span>{{end}} <span class="badge risk-high">NEW</span></div>
+        {{template "diffPorts" .OpenPorts}}
+      </article>
+      {{end}}
+    </div>
+    {{end}}
+
+    {{if .Diff.HostsRemoved}}
+    <h2>👋 Hosts Removed</h2>
+    <div class="hosts-grid">
+      {{range .Diff.HostsRemoved}}
+      <article class="host-card" data-status="down">
+        <div class="host-name"><strong class="ip">{{.Addr}}</strong>{{if .Hostname}}<span class="hostname">{{.Hostname}}</span>{{end}} <span class="badge state-down">GONE</span></div>
+        {{template "diffPorts" .OpenPorts}}
+      </article>
+      {{end}}
+    </div>
+    {{end}}
+
+    {{if .Diff.PortsOpened}}
+    <h2>🔓 Ports Opened</h2>
+    {{template "diffChanges" .Diff.PortsOpened}}
+    {{end}}
+
+    {{if .Diff.PortsClosed}}
+    <h2>🔒 Ports Closed</h2>
+    {{template "diffChanges" .Diff.PortsClosed}}
+    {{end}}
+
+    {{if .Diff.ServiceChanges}}
+    <h2>🔄 Service Changes</h2>
+    <div class="ports-table-wrap">
+      <table class="ports-table">
+        <thead><tr><th>Host</th><th>Port</th><th>Before</th><th>After</th></tr></thead>
+        <tbody>
+          {{range .Diff.ServiceChanges}}
+          <tr>
+            <td><code>{{.Addr}}</code>{{if .Hostname}} <small class="muted">{{.Hostname}}</small>{{end}}</td>
+            <td class="p-port">{{.Port}}<small class="p-proto"> {{.Protocol}}</small></td>
+            <td class="p-product">{{.Before.Service}} {{.Before.Label}}</td>
+            <td class="p-service">{{.After.Service}} {{.After.Label}}</td>
+          </tr>
+          {{end}}
+        </tbody>
+      </table>
+    </div>
+    {{end}}
+
+    <footer class="footer">
+      <small class="muted">
+        🛡️ Scan comparison generated by <strong>DefenceLogic.io</strong> • Nmap HTML Converter v1.0.0<br/>
+        📅 Exported on {{.Generated.Format "Monday, January 2, 2006 at 15:04:05"}}
+      </small>
+    </footer>
+  </main>
+</body>
+</html>
+
+{{define "diffPorts"}}
+{{if .}}
+<div class="ports-table-wrap" style="margin-top:16px;">
+  <table class="ports-table">
+    <thead><tr><th>Port</th><th>Protocol</th><th>Service</th><th>Product / Version</th></tr></thead>
+    <tbody>
+      {{range .}}
+      <tr><td class="p-port">{{.Port}}</td><td class="p-proto">{{.Protocol}}</td><td class="p-service">{{or .Service "-"}}</td><td class="p-product">{{.Label}}</td></tr>
+      {{end}}
+    </tbody>
+  </table>
+</div>
+{{else}}
+<p class="muted">No open ports</p>
+{{end}}
+{{end}}
+
+{{define "diffChanges"}}
+<div class="ports-table-wrap">
+  <table class="ports-table">
+    <thead><tr><th>Host</th><th>Port</th><th>Protocol</th><th>Service</th><th>Product / Version</th></tr></thead>
+    <tbody>
+      {{range .}}
+      <tr>
+        <td><code>{{.Addr}}</code>{{if .Hostname}} <small class="muted">{{.Hostname}}</small>{{end}}</td>
+        <td class="p-port">{{.Port}}</td><td class="p-proto">{{.Protocol}}</td>
+        <td class="p-service">{{or .Service "-"}}</td><td class="p-product">{{.Label}}</td>
+      </tr>
+      {{end}}
+    </tbody>
+  </table>
+</div>
+{{end}}`
@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestCompareScans(t *testing.T) {
+	base := []Host{
+		testHost("10.0.0.9", "up", testPort(22, "ssh")),
+		testHost("10.0.0.20", "up", testPort(80, "http")),
+		testHost("10.0.0.30", "down"),
+	}
+	cur := []Host{
+		testHost("10.0.0.9", "up", testPort(22, "ssh"), testPort(443, "https")),
+		testHost("10.0.0.10", "up", testPort(80, "http")),
+		testHost("10.0.0.100", "up"),
+		testHost("10.0.0.20", "up"),
+	}
+	d := compareScans(NmapRunInfo{}, base, NmapRunInfo{}, cur)
+
+	var added []string
+	for _, h := range d.HostsAdded {
+		added = append(added, h.Addr)
+	}
+	if len(added) != 2 || added[0] != "10.0.0.10" || added[1] != "10.0.0.100" {
+		t.Errorf("hosts added = %v, want [10.0.0.10 10.0.0.100] in numeric order", added)
+	}
+	if d.Summary.HostsRemoved != 0 {
+		t.Errorf("hosts removed = %d, want 0 (down hosts are not compared)", d.Summary.HostsRemoved)
+	}
+	if d.Summary.PortsOpened != 1 || d.PortsOpened[0].Port != 443 {
+		t.Errorf("ports opened = %+v, want 443", d.PortsOpened)
+	}
+	if d.Summary.PortsClosed != 1 || d.PortsClosed[0].Addr != "10.0.0.20" {
+		t.Errorf("ports closed = %+v, want 10.0.0.20:80", d.PortsClosed)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestWriteECSBulk(t *testing.T) {
+	closed := testPort(80, "http")
+	closed.State.State = "closed"
+	dns := testPort(53, "domain")
+	dns.Protocol = "udp"
+	web := testHost("10.0.0.1", "up", testPort(22, "ssh"), closed)
+	web.Addresses = append(web.Addresses, Address{Addr: "00:11:22:33:44:55", AddrType: "mac"})
+	web.Hostnames.Names = []Hostname{{Name: "web01.example.com"}}
+	hosts := []Host{web, testHost("10.0.0.2", "up", dns)}
+	info := NmapRunInfo{Args: "nmap -sV 10.0.0.0/30", StartTime: "1700000000"}
+
+	write := func() []byte {
+		var buf bytes.Buffer
+		if err := writeECSBulk(&buf, "nmap-scans", info, hosts); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	out := write()
+
+	type action struct {
+		Index struct {
+			Index string `json:"_index"`
+			ID    string `json:"_id"`
+		} `json:"index"`
+	}
+	var actions []action
+	var docs []map[string]interface{}
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for i := 0; sc.Scan(); i++ {
+		if i%2 == 0 {
+			var a action
+			if err := json.Unmarshal(sc.Bytes(), &a); err != nil {
+				t.Fatalf("line %d: %v", i+1, err)
+			}
+			actions = append(actions, a)
+			continue
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal(sc.Bytes(), &doc); err != nil {
+			t.Fatalf("line %d: %v", i+1, err)
+		}
+		docs = append(docs, doc)
+	}
+
+	// one document per open port; the closed port is left out
+	if len(docs) != 2 || len(actions) != 2 {
+		t.Fatalf("got %d documents and %d actions, want 2 of each", len(docs), len(actions))
+	}
+	field := func(doc map[string]interface{}, path ...string) interface{} {
+		var v interface{} = doc
+		for _, key := range path {
+			m, _ := v.(map[string]interface{})
+			v = m[key]
+		}
+		return v
+	}
+	tests := []struct {
+		doc  int
+		path []string
+		want interface{}
+	}{
+		{0, []string{"@timestamp"}, "2023-11-14T22:13:20Z"},
+		{0, []string{"host", "ip"}, []interface{}{"10.0.0.1"}},
+		{0, []string{"host", "mac"}, []interface{}{"00:11:22:33:44:55"}},
+		{0, []string{"host", "hostname"}, "web01.example.com"},
+		{0, []string{"destination", "ip"}, "10.0.0.1"},
+		{0, []string{"destination", "port"}, 22.0},
+		{0, []string{"network", "transport"}, "tcp"},
+		{0, []string{"service", "name"}, "ssh"},
+		{0, []string{"nmap", "args"}, "nmap -sV 10.0.0.0/30"},
+		{0, []string{"nmap", "state"}, "open"},
+		{1, []string{"host", "ip"}, []interface{}{"10.0.0.2"}},
+		{1, []string{"destination", "port"}, 53.0},
+		{1, []string{"network", "transport"}, "udp"},
+		{1, []string{"service", "name"}, "domain"},
+	}
+	for _, tt := range tests {
+		if got := field(docs[tt.doc], tt.path...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("doc %d %v = %v, want %v", tt.doc, tt.path, got, tt.want)
+		}
+	}
+
+	wantIDs := []string{"1700000000-10.0.0.1-tcp-22", "1700000000-10.0.0.2-udp-53"}
+	for i, a := range actions {
+		if a.Index.Index != "nmap-scans" || a.Index.ID != wantIDs[i] {
+			t.Errorf("action %d = %+v, want index nmap-scans and _id %s", i, a.Index, wantIDs[i])
+		}
+	}
+	if again := write(); !bytes.Equal(again, out) {
+		t.Error("a second run produced different output, so re-ingesting would duplicate documents")
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template" // the payload is base64 and needs no escaping
+)
+
+// pbkdf2Iterations follows the current OWASP guidance for PBKDF2-HMAC-SHA256
+const pbkdf2Iterations = 600000
+
+// passwordEnv is read when -password-file is not given
+const passwordEnv = "NMAP_REPORT_PASSWORD"
+
+// readPassword takes the first line of the file, or the environment variable.
+// There is deliberately no flag for the password itself, which would show
+// up in the process list and shell history.
+func readPassword(file string) (string, error) {
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		pw, _, _ := strings.Cut(string(b), "\n")
+		pw = strings.TrimSuffix(pw, "\r")
+		if pw == "" {
+			return "", fmt.Errorf("%s: empty password", file)
+		}
+		return pw, nil
+	}
+	if pw := os.Getenv(passwordEnv); pw != "" {
+		return pw, nil
+	}
+	return "", fmt.Errorf("set %s or pass -password-file", passwordEnv)
+}
+
+// pbkdf2SHA256 derives a key as in RFC 8018, matching WebCrypto's PBKDF2
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	var key []byte
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.Write(prf, binary.BigEndian, block)
+		u := prf.Sum(nil)
+		t := append([]byte(nil), u...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
+
+// EncryptedPayload is embedded in the decryption page; every field but the
+// ciphertext is needed to derive the key and is not secret
+type EncryptedPayload struct {
+	Iterations int
+	Salt       string // base64
+	IV         string // base64, 96-bit GCM nonce
+	Data       string // base64 of AES-256-GCM(gzip(report))
+}
+
+// encryptReport gzips the rendered report and seals it with AES-256-GCM
+// under a PBKDF2-SHA256 key from the password
+func encryptReport(report []byte, password string) (EncryptedPayload, error) {
+	var zipped bytes.Buffer
+	zw := gzip.NewWriter(&zipped)
+	if _, err := zw.Write(report); err != nil {
+		return EncryptedPayload{}, err
+	}
+	if err := zw.Close(); err != nil {
+		return EncryptedPayload{}, err
+	}
+
+	salt := make([]byte, 16)
+	iv := make([]byte, 12)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return EncryptedPayload{}, err
+	}
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return EncryptedPayload{}, err
+	}
+	block, err := aes.NewCipher(pbkdf2SHA256([]byte(password), salt, pbkdf2Iterations, 32))
+	if err != nil {
+		return EncryptedPayload{}, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return EncryptedPayload{}, err
+	}
+	enc := base64.StdEncoding
+	return EncryptedPayload{
+		Iterations: pbkdf2Iterations,
+		Salt:       enc.EncodeToString(salt),
+		IV:         enc.EncodeToString(iv),
+		Data:       enc.EncodeToString(gcm.Seal(nil, iv, zipped.Bytes(), nil)),
+	}, nil
+}
+
+// writeEncryptedReport writes the password page carrying the payload
+func writeEncryptedReport(w io.Writer, report []byte, password string) error {
+	payload, err := encryptReport(report, password)
+	if err != nil {
+		return fmt.Errorf("encrypt: %w", err)
+	}
+	return encryptedPage.Execute(w, payload)
+}
+
+// encryptedPage decrypts with WebCrypto and replaces itself with the report.
+// It carries no scan details, so the file reveals nothing without the password.
+var encryptedPage = template.Must(template.New("encrypted").Parse(`<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width,initial-scale=1">
+<title>Encrypted report</title>
+<style>
+body{margin:0;min-height:100vh;display:flex;align-items:center;justify-content:center;background:#0b1220;color:#e2e8f0;font-family:-apple-system,BlinkMacSystemFont,"Segoe UI",Roboto,sans-serif}
+form{background:rgba(255,255,255,0.04);border:1px solid rgba(255,255,255,0.1);border-radius:16px;padding:32px;width:320px;box-shadow:0 8px 32px rgba(0,0,0,0.3)}
+h1{font-size:18px;margin:0 0 6px 0}
+p{color:#94a3b8;font-size:13px;margin:0 0 18px 0}
+input{width:100%;box-sizing:border-box;padding:10px 12px;border-radius:8px;border:1px solid rgba(255,255,255,0.15);background:rgba(0,0,0,0.3);color:inherit;font-size:14px}
+button{margin-top:12px;width:100%;padding:10px;border:0;border-radius:8px;background:#38bdf8;color:#022;font-weight:600;font-size:14px;cursor:pointer}
+button:disabled{opacity:0.6;cursor:wait}
+#error{color:#fb7185;font-size:13px;margin-top:10px;min-height:1em}
+</style>
+</head>
+<body>
+<form id="unlock">
+  <h1>🔒 Encrypted Nmap report</h1>
+  <p>Enter the password to open the report.</p>
+  <input type="password" id="password" autocomplete="current-password" autofocus required>
+  <button type="submit" id="open">Open report</button>
+  <div id="error" role="alert"></div>
+</form>
+<script id="payload" type="application/json">{"iterations":{{.Iterations}},"salt":"{{.Salt}}","iv":"{{.IV}}","data":"{{.Data}}"}</script>
+<script>
+(function(){
+  const payload = JSON.parse(document.getElementById('payload').textContent);
+  const bytes = b64 => Uint8Array.from(atob(b64), c => c.charCodeAt(0));
+  const form = document.getElementById('unlock');
+  const button = document.getElementById('open');
+  const error = document.getElementById('error');
+
+  if(!window.crypto || !crypto.subtle || !window.DecompressionStream) {
+    error.textContent = 'This browser cannot decrypt the report (needs WebCrypto over https or file://).';
+    button.disabled = true;
+  }
+
+  form.addEventListener('submit', async e => {
+    e.preventDefault();
+    button.disabled = true;
+    error.textContent = '';
+    button.textContent = 'Decrypting…';
+    try {
+      const password = new TextEncoder().encode(document.getElementById('password').value);
+      const base = await crypto.subtle.importKey('raw', password, 'PBKDF2', false, ['deriveKey']);
+      const key = await crypto.subtle.deriveKey(
+        {name: 'PBKDF2', hash: 'SHA-256', salt: bytes(payload.salt), iterations: payload.iterations},
+        base, {name: 'AES-GCM', length: 256}, false, ['decrypt']);
+      let zipped;
+      try {
+        zipped = await crypto.subtle.decrypt({name: 'AES-GCM', iv: bytes(payload.iv)}, key, bytes(payload.data));
+      } catch(_) {
+        throw new Error('Wrong password.');
+      }
+      const stream = new Blob([zipped]).stream().pipeThrough(new DecompressionStream('gzip'));
+      const html = await new Response(stream).text();
+      document.open();
+      document.write(html);
+      document.close();
+    } catch(err) {
+      error.textContent = err.message || String(err);
+      button.disabled = false;
+      button.textContent = 'Open report';
+    }
+  });
+})();
+</script>
+</body>
+</html>
+`))
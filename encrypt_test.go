@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestPBKDF2SHA256(t *testing.T) {
+	// RFC 7914 section 11, and the common 4096-iteration vector
+	tests := []struct {
+		password, salt string
+		iterations     int
+		want           string
+	}{
+		{"passwd", "salt", 1, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+		{"password", "salt", 4096, "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+	}
+	for _, tt := range tests {
+		got := pbkdf2SHA256([]byte(tt.password), []byte(tt.salt), tt.iterations, len(tt.want)/2)
+		if hex.EncodeToString(got) != tt.want {
+			t.Errorf("pbkdf2(%q, %q, %d) = %x", tt.password, tt.salt, tt.iterations, got)
+		}
+	}
+}
+
+// decryptPayload does what the page's WebCrypto code does
+func decryptPayload(p EncryptedPayload, password string) ([]byte, error) {
+	dec := base64.StdEncoding
+	salt, _ := dec.DecodeString(p.Salt)
+	iv, _ := dec.DecodeString(p.IV)
+	data, _ := dec.DecodeString(p.Data)
+	block, err := aes.NewCipher(pbkdf2SHA256([]byte(password), salt, p.Iterations, 32))
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	zipped, err := gcm.Open(nil, iv, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(zipped))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(zr)
+}
+
+func TestEncryptReportRoundTrip(t *testing.T) {
+	report := []byte("<html><body>10.0.0.1 22/tcp open ssh</body></html>")
+	var page bytes.Buffer
+	if err := writeEncryptedReport(&page, report, "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(page.Bytes(), []byte("10.0.0.1")) {
+		t.Fatal("encrypted page contains scan data")
+	}
+
+	m := regexp.MustCompile(`<script id="payload" type="application/json">(.*?)</script>`).FindSubmatch(page.Bytes())
+	if m == nil {
+		t.Fatal("no payload in the page")
+	}
+	var payload struct {
+		Iterations int    `json:"iterations"`
+		Salt       string `json:"salt"`
+		IV         string `json:"iv"`
+		Data       string `json:"data"`
+	}
+	if err := json.Unmarshal(m[1], &payload); err != nil {
+		t.Fatalf("parse payload: %v", err)
+	}
+	p := EncryptedPayload(payload)
+	if p.Iterations != pbkdf2Iterations {
+		t.Errorf("iterations = %d, want %d", p.Iterations, pbkdf2Iterations)
+	}
+
+	got, err := decryptPayload(p, "correct horse")
+	if err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	if !bytes.Equal(got, report) {
+		t.Errorf("decrypted %q, want %q", got, report)
+	}
+	if _, err := decryptPayload(p, "wrong horse"); err == nil {
+		t.Error("wrong password decrypted the report")
+	}
+}
+
+func TestReadPassword(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "pw")
+	os.WriteFile(file, []byte("s3cret\r\nsecond line\n"), 0o600)
+	empty := filepath.Join(dir, "empty")
+	os.WriteFile(empty, []byte("\n"), 0o600)
+
+	t.Setenv(passwordEnv, "from-env")
+	if pw, err := readPassword(file); err != nil || pw != "s3cret" {
+		t.Errorf("file: %q, %v", pw, err)
+	}
+	if pw, err := readPassword(""); err != nil || pw != "from-env" {
+		t.Errorf("env: %q, %v", pw, err)
+	}
+	if _, err := readPassword(empty); err == nil {
+		t.Error("empty password file accepted")
+	}
+	t.Setenv(passwordEnv, "")
+	if _, err := readPassword(""); err == nil {
+		t.Error("no password source accepted")
+	}
+}
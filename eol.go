@@ -0,0 +1,233 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// eolJSON is the bundled lifecycle dataset; -eol-data replaces it. The
+// dates come from the vendors' lifecycle pages (Microsoft Lifecycle, the
+// Apache httpd and PHP release announcements), cross-checked against
+// endoflife.date. Products whose vendor publishes no per-release end of
+// life, such as OpenSSH, are left out rather than given guessed dates.
+//
+//go:embed eol.json
+var eolJSON []byte
+
+// EOLDataset lists release cycles and their end-of-life dates per product
+type EOLDataset struct {
+	Updated  string       `json:"updated"`
+	Products []EOLProduct `json:"products"`
+}
+
+// EOLProduct is matched by nmap product name (case-insensitive) or by CPE
+// prefix, on services and on OS classes
+type EOLProduct struct {
+	Name     string       `json:"name"`
+	Products []string     `json:"products"`
+	CPEs     []string     `json:"cpes"`
+	Releases []EOLRelease `json:"releases"`
+}
+
+// EOLRelease is one cycle; the cycle is a version prefix ("2.2" matches
+// 2.2.34), an empty cycle matches any version. An empty EOL date means the
+// cycle is still supported.
+type EOLRelease struct {
+	Cycle string `json:"cycle"`
+	EOL   string `json:"eol"`
+}
+
+// EOLStatus records unsupported software found on a port or host
+type EOLStatus struct {
+	Product string `json:"product"`
+	Cycle   string `json:"cycle,omitempty"`
+	Version string `json:"version,omitempty"`
+	EOL     string `json:"eol"`
+}
+
+// Label is the product and cycle, e.g. "Apache httpd 2.2"
+func (e EOLStatus) Label() string {
+	return strings.TrimSpace(e.Product + " " + e.Cycle)
+}
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// finding turns the status into a report finding, one issue per product cycle
+func (e EOLStatus) finding() Finding {
+	id := strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(e.Label()), "-"), "-")
+	return Finding{
+		RuleID:      "eol-" + id,
+		Title:       e.Label() + " is end-of-life",
+		Level:       RiskHigh,
+		Score:       30,
+		Description: fmt.Sprintf("%s reached end of life on %s and no longer receives security fixes.", e.Label(), e.EOL),
+		Remediation: "Upgrade to a supported release or replace the system.",
+	}
+}
+
+// eolData is the active dataset used by enrichHost
+var eolData = mustParseEOL(eolJSON)
+
+func mustParseEOL(b []byte) *EOLDataset {
+	d, err := parseEOL(b)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func parseEOL(b []byte) (*EOLDataset, error) {
+	var d EOLDataset
+	if err := json.Unmarshal(b, &d); err != nil {
+		return nil, err
+	}
+	for _, p := range d.Products {
+		for _, r := range p.Releases {
+			if r.EOL == "" {
+				continue
+			}
+			if _, err := time.Parse("2006-01-02", r.EOL); err != nil {
+				return nil, fmt.Errorf("%s %s: eol date must be YYYY-MM-DD, got %q", p.Name, r.Cycle, r.EOL)
+			}
+		}
+	}
+	return &d, nil
+}
+
+// loadEOLFile replaces the bundled dataset
+func loadEOLFile(path string) (*EOLDataset, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	d, err := parseEOL(b)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return d, nil
+}
+
+// versionHasPrefix reports whether version belongs to cycle, comparing
+// version components so "2.4" matches 2.4.49 but not 2.40
+func versionHasPrefix(version, cycle string) bool {
+	v, c := versionParts(version), versionParts(cycle)
+	if len(c) > len(v) {
+		return false
+	}
+	for i := range c {
+		if compareVersions(v[i], c[i]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup finds the release cycle for a version. Versions older than every
+// listed cycle are taken to be unsupported since the oldest cycle's date.
+func (p EOLProduct) lookup(version string) (EOLRelease, bool) {
+	var best EOLRelease
+	found := false
+	for _, r := range p.Releases {
+		if r.Cycle == "" || (version != "" && versionHasPrefix(version, r.Cycle)) {
+			if !found || len(versionParts(r.Cycle)) > len(versionParts(best.Cycle)) {
+				best, found = r, true
+			}
+		}
+	}
+	if found || version == "" || len(p.Releases) == 0 {
+		return best, found
+	}
+	oldest := p.Releases[0]
+	for _, r := range p.Releases[1:] {
+		if compareVersions(r.Cycle, oldest.Cycle) < 0 {
+			oldest = r
+		}
+	}
+	if oldest.EOL != "" && compareVersions(version, oldest.Cycle) < 0 {
+		return EOLRelease{Cycle: "< " + oldest.Cycle, EOL: oldest.EOL}, true
+	}
+	return best, false
+}
+
+// check returns the EOL status for the first product matching one of the
+// CPEs or the product name, nil when the software is supported or unknown
+func (d *EOLDataset) check(cpes []string, product, version string, now time.Time) *EOLStatus {
+	if d == nil {
+		return nil
+	}
+	for _, p := range d.Products {
+		v, ok := p.matchCPE(cpes)
+		if !ok {
+			if !p.matchProduct(product) {
+				continue
+			}
+			v = version
+		}
+		if v == "" {
+			v = version
+		}
+		r, ok := p.lookup(v)
+		if !ok || r.EOL == "" {
+			return nil
+		}
+		date, _ := time.Parse("2006-01-02", r.EOL)
+		if date.After(now) {
+			return nil
+		}
+		return &EOLStatus{Product: p.Name, Cycle: r.Cycle, Version: v, EOL: r.EOL}
+	}
+	return nil
+}
+
+// matchCPE returns the version part of the first CPE starting with one of
+// the product's prefixes
+func (p EOLProduct) matchCPE(cpes []string) (string, bool) {
+	for _, cpe := range cpes {
+		for _, prefix := range p.CPEs {
+			if cpe == prefix || strings.HasPrefix(strings.ToLower(cpe), strings.ToLower(prefix)+":") {
+				c, _ := parseCPE(cpe)
+				return c.Version, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (p EOLProduct) matchProduct(product string) bool {
+	for _, name := range p.Products {
+		if product != "" && strings.EqualFold(name, product) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstWord trims distribution suffixes such as "7.4p1 Debian 10+deb9u7"
+func firstWord(s string) string {
+	if f := strings.Fields(s); len(f) > 0 {
+		return strings.ToLower(f[0])
+	}
+	return ""
+}
+
+// checkPortEOL looks at the service product, version and CPEs
+func checkPortEOL(p Port, now time.Time) *EOLStatus {
+	return eolData.check(p.Service.CPEs, p.Service.Product, firstWord(p.Service.Version), now)
+}
+
+// checkHostEOL looks at the CPEs of the best OS match
+func checkHostEOL(h Host, now time.Time) *EOLStatus {
+	if len(h.OS.Matches) == 0 {
+		return nil
+	}
+	var cpes []string
+	for _, c := range h.OS.Matches[0].Classes {
+		cpes = append(cpes, c.CPEs...)
+	}
+	return eolData.check(cpes, "", "", now)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEOLCheck(t *testing.T) {
+	data := mustParseEOL(eolJSON)
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name             string
+		cpes             []string
+		product, version string
+		want             string // Label() of the status, "" when supported or unknown
+	}{
+		{"old cycle by product", nil, "Apache httpd", "2.2.34", "Apache httpd 2.2"},
+		{"supported cycle", nil, "Apache httpd", "2.4.58", ""},
+		{"older than every cycle", nil, "Apache httpd", "1.2", "Apache httpd < 1.3"},
+		{"version from CPE", []string{"cpe:/a:php:php:7.4.33"}, "", "", "PHP 7.4"},
+		{"EOL date in the future", nil, "PHP", "8.2.10", ""},
+		{"OS CPE without cycle", []string{"cpe:/o:microsoft:windows_server_2008::sp2"}, "", "", "Windows Server 2008"},
+		{"no data for OpenSSH", []string{"cpe:/a:openbsd:openssh:6.6"}, "OpenSSH", "6.6", ""},
+		{"unknown product", nil, "nginx", "1.0", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if s := data.check(tt.cpes, tt.product, tt.version, now); s != nil {
+			got = s.Label()
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
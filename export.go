@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+)
+
+// exportOptions carries the flag values the non-HTML writers need
+type exportOptions struct {
+	Source string // input XML path, recorded by the database writer
+	Policy ExposurePolicy
+	Index  string // ecs: target index named in each bulk action
+	PostTo string // ecs: optional _bulk endpoint to send the documents to
+	Syslog string // cef/leef: optional udp:// or tcp:// syslog target
+}
+
+// defaultOutputs gives each format a sensible output name when -out is not set
+var defaultOutputs = map[string]string{
+	"html":    "nmap.html",
+	"junit":   "nmap-junit.xml",
+	"sqlite":  "scans.db",
+	"ecs":     "nmap-bulk.ndjson",
+	"cef":     "nmap-events.cef",
+	"leef":    "nmap-events.leef",
+	"targets": "targets",
+}
+
+// runExport loads the whole scan and writes it in one of the non-HTML formats
+func runExport(format string, in io.Reader, outPath string, opts exportOptions) error {
+	if _, ok := defaultOutputs[format]; !ok || format == "html" {
+		return fmt.Errorf("unknown output format %q", format)
+	}
+
+	info, hosts, err := loadScan(in)
+	if err != nil {
+		return err
+	}
+
+	// these write to a database file or a directory rather than a stream
+	switch format {
+	case "sqlite":
+		// the database is appended to in place rather than recreated
+		return writeSQLite(outPath, opts.Source, info, hosts)
+	case "targets":
+		return writeTargets(outPath, hosts)
+	}
+
+	outFile, err := createOutput(outPath)
+	if err != nil {
+		return fmt.Errorf("create output: %w", err)
+	}
+	defer outFile.Close()
+	w := bufio.NewWriter(outFile)
+
+	switch format {
+	case "junit":
+		err = writeJUnit(w, info, hosts, opts.Policy)
+	case "ecs":
+		var buf bytes.Buffer
+		if err = writeECSBulk(&buf, opts.Index, info, hosts); err == nil {
+			_, err = w.Write(buf.Bytes())
+		}
+		if err == nil && opts.PostTo != "" {
+			if err = postBulk(opts.PostTo, buf.Bytes()); err != nil {
+				err = fmt.Errorf("post to %s: %w", redactURL(opts.PostTo), err)
+			}
+		}
+	case "cef", "leef":
+		events := buildSIEMEvents(info, hosts, opts.Policy)
+		err = writeSIEMEvents(w, format, events)
+		if err == nil && opts.Syslog != "" {
+			if err = sendSyslog(opts.Syslog, format, events); err != nil {
+				err = fmt.Errorf("send to %s: %w", opts.Syslog, err)
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("write %s: %w", format, err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("write %s: %w", format, err)
+	}
+	return outFile.Close()
+}
+
+// redactURL hides the password of a URL given on the command line, so it
+// stays out of error messages and CI logs
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "-es-url"
+	}
+	return u.Redacted()
+}
+
+// createOutput opens the output file, treating "-" as stdout
+func createOutput(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
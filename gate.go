@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Exit statuses for CI use. 1 is left to fatal errors and 2 to flag parsing.
+// When several gates fail, Evaluate picks the status in the order no hosts,
+// -fail-on, -compliance, -max-open-ports (6, 4, 3, 5).
+const (
+	exitComplianceFailed = 3 // a host breaks the -compliance baseline
+	exitFailOn           = 4 // a finding at or above -fail-on
+	exitMaxOpenPorts     = 5 // more open ports than -max-open-ports
+	exitNoHosts          = 6 // the scan had no hosts, so nothing was gated
+)
+
+// Gate holds the CI thresholds; the zero value gates nothing
+type Gate struct {
+	FailOn       string // minimum finding level that fails the run, "" to disable
+	MaxOpenPorts int    // -1 to disable
+}
+
+// Enabled reports whether a summary should be printed at all
+func (g Gate) Enabled() bool {
+	return g.FailOn != "" || g.MaxOpenPorts >= 0
+}
+
+// parseFailOn validates a -fail-on level
+func parseFailOn(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "", RiskLow, RiskMedium, RiskHigh, RiskCritical:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown level %q (want low, medium, high or critical)", s)
+}
+
+// GateSummary is the machine-readable result written to stderr as one JSON line
+type GateSummary struct {
+	Result     string             `json:"result"` // pass or fail
+	ExitCode   int                `json:"exit_code"`
+	Failed     []string           `json:"failed,omitempty"` // gates that tripped, e.g. "fail-on=critical"
+	Hosts      int                `json:"hosts"`
+	OpenPorts  int                `json:"open_ports"`
+	Findings   map[string]int     `json:"findings"` // count per level
+	Compliance *ComplianceSummary `json:"compliance,omitempty"`
+}
+
+// ComplianceSummary is the -compliance part of the gate summary
+type ComplianceSummary struct {
+	Passed       bool `json:"passed"`
+	Checked      int  `json:"hosts_checked"`
+	FailingHosts int  `json:"hosts_failing"`
+	Unexpected   int  `json:"unexpected_open"`
+	Missing      int  `json:"expected_missing"`
+	Uncovered    int  `json:"not_covered"`
+}
+
+// Evaluate checks the report against the thresholds and the compliance
+// baseline, if any. A report without hosts always fails: an empty or
+// truncated scan must not pass a CI gate.
+func (g Gate) Evaluate(hosts, openPorts int, findings []ReportFinding, compliance *ComplianceReport) GateSummary {
+	s := GateSummary{
+		Result:    "pass",
+		Hosts:     hosts,
+		OpenPorts: openPorts,
+		Findings:  map[string]int{RiskCritical: 0, RiskHigh: 0, RiskMedium: 0, RiskLow: 0, RiskInfo: 0},
+	}
+	failOn := false
+	for _, f := range findings {
+		s.Findings[f.Level]++
+		if g.FailOn != "" && riskRank(f.Level) >= riskRank(g.FailOn) {
+			failOn = true
+		}
+	}
+
+	fail := func(gate string, code int) {
+		s.Failed = append(s.Failed, gate)
+		if s.ExitCode == 0 {
+			s.ExitCode = code
+		}
+	}
+	if hosts == 0 {
+		fail("no-hosts", exitNoHosts)
+	}
+	if failOn {
+		fail("fail-on="+g.FailOn, exitFailOn)
+	}
+	if compliance != nil {
+		s.Compliance = &ComplianceSummary{
+			Passed:       compliance.Passed(),
+			Checked:      len(compliance.Hosts),
+			FailingHosts: len(compliance.Failures()),
+			Unexpected:   compliance.UnexpectedCount(),
+			Missing:      compliance.MissingCount(),
+			Uncovered:    compliance.Uncovered,
+		}
+		if !s.Compliance.Passed {
+			fail("compliance", exitComplianceFailed)
+		}
+	}
+	if g.MaxOpenPorts >= 0 && openPorts > g.MaxOpenPorts {
+		fail(fmt.Sprintf("max-open-ports=%d", g.MaxOpenPorts), exitMaxOpenPorts)
+	}
+	if s.ExitCode != 0 {
+		s.Result = "fail"
+	}
+	return s
+}
+
+// Write prints the summary as a single JSON line
+func (s GateSummary) Write(w io.Writer) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
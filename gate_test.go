@@ -0,0 +1,60 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGateEvaluate(t *testing.T) {
+	high := []ReportFinding{{Finding: Finding{RuleID: "cleartext-ftp", Level: RiskHigh}}}
+	failing := &ComplianceReport{Hosts: []HostCompliance{{Missing: []PortSpec{{Port: 443}}}}}
+	passing := &ComplianceReport{Hosts: []HostCompliance{{}}}
+	tests := []struct {
+		name       string
+		gate       Gate
+		hosts      int
+		openPorts  int
+		findings   []ReportFinding
+		compliance *ComplianceReport
+		wantCode   int
+		wantFailed []string
+	}{
+		{"nothing gated", Gate{MaxOpenPorts: -1}, 3, 10, high, nil, 0, nil},
+		{"below fail-on", Gate{FailOn: RiskCritical, MaxOpenPorts: -1}, 3, 10, high, nil, 0, nil},
+		{"fail-on", Gate{FailOn: RiskHigh, MaxOpenPorts: -1}, 3, 10, high, nil, exitFailOn, []string{"fail-on=high"}},
+		{"max open ports", Gate{MaxOpenPorts: 5}, 3, 10, nil, nil, exitMaxOpenPorts, []string{"max-open-ports=5"}},
+		{"at max open ports", Gate{MaxOpenPorts: 10}, 3, 10, nil, nil, 0, nil},
+		{"compliance passed", Gate{MaxOpenPorts: -1}, 3, 10, nil, passing, 0, nil},
+		{"compliance failed", Gate{MaxOpenPorts: -1}, 3, 10, nil, failing, exitComplianceFailed, []string{"compliance"}},
+		{"fail-on decides", Gate{FailOn: RiskLow, MaxOpenPorts: 1}, 3, 10, high, failing, exitFailOn,
+			[]string{"fail-on=low", "compliance", "max-open-ports=1"}},
+		{"compliance decides over max open ports", Gate{MaxOpenPorts: 1}, 3, 10, nil, failing, exitComplianceFailed,
+			[]string{"compliance", "max-open-ports=1"}},
+		{"no hosts", Gate{FailOn: RiskCritical, MaxOpenPorts: -1}, 0, 0, nil, nil, exitNoHosts, []string{"no-hosts"}},
+		{"no hosts with compliance", Gate{MaxOpenPorts: -1}, 0, 0, nil, &ComplianceReport{}, exitNoHosts, []string{"no-hosts", "compliance"}},
+	}
+	for _, tt := range tests {
+		s := tt.gate.Evaluate(tt.hosts, tt.openPorts, tt.findings, tt.compliance)
+		if s.ExitCode != tt.wantCode || !reflect.DeepEqual(s.Failed, tt.wantFailed) {
+			t.Errorf("%s: exit %d, failed %v; want %d, %v", tt.name, s.ExitCode, s.Failed, tt.wantCode, tt.wantFailed)
+		}
+		wantResult := "pass"
+		if tt.wantCode != 0 {
+			wantResult = "fail"
+		}
+		if s.Result != wantResult {
+			t.Errorf("%s: result %q, want %q", tt.name, s.Result, wantResult)
+		}
+	}
+}
+
+func TestParseFailOn(t *testing.T) {
+	for _, in := range []string{"", "low", " High ", "critical"} {
+		if _, err := parseFailOn(in); err != nil {
+			t.Errorf("parseFailOn(%q): %v", in, err)
+		}
+	}
+	if _, err := parseFailOn("info"); err == nil {
+		t.Error("parseFailOn(info) succeeded")
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// JUnit XML structures, following the de-facto schema understood by Jenkins,
+// GitLab, GitHub Actions reporters and friends
+type JUnitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []JUnitTestSuite `xml:"testsuite"`
+}
+
+type JUnitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Hostname  string          `xml:"hostname,attr,omitempty"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []JUnitTestCase `xml:"testcase"`
+}
+
+type JUnitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *JUnitFailure `xml:"failure,omitempty"`
+}
+
+type JUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// buildJUnit evaluates the policy against every host that is up: one
+// testsuite per host, one testcase per policy check
+func buildJUnit(info NmapRunInfo, hosts []Host, policy ExposurePolicy) JUnitTestSuites {
+	report := JUnitTestSuites{Name: "nmap exposure policy"}
+	var timestamp string
+	if started := info.Started(); !started.IsZero() {
+		timestamp = started.UTC().Format("2006-01-02T15:04:05")
+	}
+
+	for _, h := range hosts {
+		if h.Status.State != "up" {
+			continue // nothing to check on a host that did not answer
+		}
+		addr := h.PrimaryAddr()
+		suite := JUnitTestSuite{
+			Name:      addr,
+			Hostname:  h.PrimaryName(),
+			Timestamp: timestamp,
+		}
+		for _, check := range policy.Evaluate(h) {
+			tc := JUnitTestCase{Name: check.Name, ClassName: "nmap." + addr}
+			if !check.Passed() {
+				lines := make([]string, len(check.Violations))
+				for i, p := range check.Violations {
+					lines[i] = addr + " " + p.Summary()
+				}
+				tc.Failure = &JUnitFailure{
+					Message: fmt.Sprintf("%d port(s) violate %q", len(check.Violations), check.Name),
+					Type:    "ExposurePolicyViolation",
+					Text:    strings.Join(lines, "\n"),
+				}
+				suite.Failures++
+			}
+			suite.Tests++
+			suite.Cases = append(suite.Cases, tc)
+		}
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Suites = append(report.Suites, suite)
+	}
+	return report
+}
+
+// writeJUnit renders the policy results as JUnit XML
+func writeJUnit(w io.Writer, info NmapRunInfo, hosts []Host, policy ExposurePolicy) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(buildJUnit(info, hosts, policy)); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestBuildJUnit(t *testing.T) {
+	policy := ExposurePolicy{DeniedServices: []string{"telnet"}, AllowedPorts: []PortSpec{{Port: 22}}}
+	hosts := []Host{
+		testHost("10.0.0.1", "up", testPort(22, "ssh")),
+		testHost("10.0.0.2", "up", testPort(23, "telnet")),
+		testHost("10.0.0.3", "down"),
+	}
+	report := buildJUnit(NmapRunInfo{}, hosts, policy)
+
+	if len(report.Suites) != 2 {
+		t.Fatalf("got %d suites, want 2 (down hosts skipped)", len(report.Suites))
+	}
+	tests := []struct {
+		host     string
+		failures int
+	}{
+		{"10.0.0.1", 0},
+		{"10.0.0.2", 2}, // denied service and not allowed
+	}
+	for i, tt := range tests {
+		s := report.Suites[i]
+		if s.Name != tt.host || s.Failures != tt.failures {
+			t.Errorf("suite %d = %s with %d failures, want %s with %d", i, s.Name, s.Failures, tt.host, tt.failures)
+		}
+	}
+	if report.Failures != 2 {
+		t.Errorf("report failures = %d, want 2", report.Failures)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// testHost builds a host with one IPv4 address; ports are open unless set
+func testHost(addr, state string, ports ...Port) Host {
+	for i := range ports {
+		if ports[i].State.State == "" {
+			ports[i].State.State = "open"
+		}
+		if ports[i].Protocol == "" {
+			ports[i].Protocol = "tcp"
+		}
+	}
+	return Host{
+		Addresses: []Address{{Addr: addr, AddrType: "ipv4"}},
+		Status:    Status{State: state},
+		Ports:     Ports{Ports: ports},
+	}
+}
+
+// testPort is an open TCP port running the named service
+func testPort(port int, service string) Port {
+	return Port{Protocol: "tcp", PortId: port, State: State{State: "open"}, Service: Service{Name: service}}
+}
+
+// testScanXML is a minimal nmap run with one host up and one down
+const testScanXML = `<?xml version="1.0"?>
+<nmaprun scanner="nmap" args="nmap -sV 10.0.0.0/30" start="1700000000" startstr="Tue Nov 14 22:13:20 2023">
+<host><status state="up"/><address addr="10.0.0.1" addrtype="ipv4"/>
+<ports><port protocol="tcp" portid="22"><state state="open"/><service name="ssh"/></port>
+<port protocol="tcp" portid="23"><state state="open"/><service name="telnet"/></port></ports></host>
+<host><status state="down"/><address addr="10.0.0.2" addrtype="ipv4"/></host>
+</nmaprun>`
+
+func TestRenderHTMLSinglePass(t *testing.T) {
+	tpl, err := loadTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	// a plain reader, as with stdin: it cannot be reopened for a second pass
+	res, err := renderHTML(&out, strings.NewReader(testScanXML), htmlOptions{Template: tpl, CSS: defaultCSS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Hosts != 2 || res.OpenPorts != 2 {
+		t.Errorf("hosts = %d, open ports = %d; want 2, 2", res.Hosts, res.OpenPorts)
+	}
+	for _, want := range []string{"nmap -sV 10.0.0.0/30", "10.0.0.1", "10.0.0.2"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report does not contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NVDIndex maps "vendor:product" to the vulnerable CPE ranges of every CVE
+// in a local NVD feed directory, so CPE matching works without network access
+type NVDIndex struct {
+	byProduct map[string][]nvdMatch
+	CVEs      int
+}
+
+// nvdMatch is one vulnerable cpeMatch entry: either an exact version from
+// the CPE string or a version range ("*" plus start/end bounds). Config is
+// the configuration the entry belongs to, which must hold as a whole.
+type nvdMatch struct {
+	CVE            string
+	CVSS           float64
+	Version        string
+	StartIncluding string
+	StartExcluding string
+	EndIncluding   string
+	EndExcluding   string
+	Config         *nvdCond
+}
+
+// nvdCond is a configuration node: its CPE entries and child nodes joined
+// by AND or OR. A typical AND node pairs the vulnerable application with
+// the platform it must run on, e.g. an app only affected on Windows.
+type nvdCond struct {
+	And      bool
+	Negate   bool
+	CPEs     []nvdCPE
+	Children []*nvdCond
+}
+
+// nvdCPE is one CPE entry of a configuration. Entries that are not
+// vulnerable name the platform and are only used to evaluate it.
+type nvdCPE struct {
+	Key        string // vendor:product
+	Vulnerable bool
+	Match      nvdMatch
+}
+
+// eval reports whether the host CPEs satisfy the configuration
+func (c *nvdCond) eval(host []cpeName) bool {
+	var results []bool
+	for _, e := range c.CPEs {
+		hit := false
+		for _, h := range host {
+			if h.key() == e.Key && e.Match.matchesHost(h.Version) {
+				hit = true
+				break
+			}
+		}
+		results = append(results, hit)
+	}
+	for _, child := range c.Children {
+		results = append(results, child.eval(host))
+	}
+	ok := c.And
+	for _, r := range results {
+		if c.And && !r {
+			ok = false
+			break
+		}
+		if !c.And && r {
+			ok = true
+			break
+		}
+	}
+	return ok != c.Negate
+}
+
+// nvdCPEMatch covers the field names of both the 1.1 feeds (cpe23Uri) and
+// the 2.0 API (criteria)
+type nvdCPEMatch struct {
+	Vulnerable            bool   `json:"vulnerable"`
+	CPE23URI              string `json:"cpe23Uri"`
+	Criteria              string `json:"criteria"`
+	VersionStartIncluding string `json:"versionStartIncluding"`
+	VersionStartExcluding string `json:"versionStartExcluding"`
+	VersionEndIncluding   string `json:"versionEndIncluding"`
+	VersionEndExcluding   string `json:"versionEndExcluding"`
+}
+
+type nvdNode struct {
+	Operator   string        `json:"operator"`
+	Negate     bool          `json:"negate"`
+	CPEMatch11 []nvdCPEMatch `json:"cpe_match"`
+	CPEMatch20 []nvdCPEMatch `json:"cpeMatch"`
+	Children   []nvdNode     `json:"children"`
+}
+
+type nvdCVSSData struct {
+	BaseScore float64 `json:"baseScore"`
+}
+
+// nvdFeed11 is the legacy nvdcve-1.1-*.json feed layout
+type nvdFeed11 struct {
+	Items []struct {
+		CVE struct {
+			Meta struct {
+				ID string `json:"ID"`
+			} `json:"CVE_data_meta"`
+		} `json:"cve"`
+		Configurations struct {
+			Nodes []nvdNode `json:"nodes"`
+		} `json:"configurations"`
+		Impact struct {
+			V3 struct {
+				CVSS nvdCVSSData `json:"cvssV3"`
+			} `json:"baseMetricV3"`
+			V2 struct {
+				CVSS nvdCVSSData `json:"cvssV2"`
+			} `json:"baseMetricV2"`
+		} `json:"impact"`
+	} `json:"CVE_Items"`
+}
+
+// nvdFeed20 is the NVD 2.0 API / feed layout
+type nvdFeed20 struct {
+	Vulnerabilities []struct {
+		CVE struct {
+			ID      string `json:"id"`
+			Metrics struct {
+				V31 []struct {
+					Data nvdCVSSData `json:"cvssData"`
+				} `json:"cvssMetricV31"`
+				V30 []struct {
+					Data nvdCVSSData `json:"cvssData"`
+				} `json:"cvssMetricV30"`
+				V2 []struct {
+					Data nvdCVSSData `json:"cvssData"`
+				} `json:"cvssMetricV2"`
+			} `json:"metrics"`
+			Configurations []struct {
+				Operator string    `json:"operator"`
+				Negate   bool      `json:"negate"`
+				Nodes    []nvdNode `json:"nodes"`
+			} `json:"configurations"`
+		} `json:"cve"`
+	} `json:"vulnerabilities"`
+}
+
+// nvdFeed decodes either layout in a single pass; only one of the two
+// lists is present in a feed file
+type nvdFeed struct {
+	nvdFeed11
+	nvdFeed20
+}
+
+// nvdIndex is the loaded -nvd feed, nil when CPE matching is off
+var nvdIndex *NVDIndex
+
+// loadNVDIndex reads every .json and .json.gz feed file in dir
+func loadNVDIndex(dir string) (*NVDIndex, error) {
+	var files []string
+	for _, pattern := range []string{"*.json", "*.json.gz"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .json or .json.gz feed files in %s", dir)
+	}
+
+	idx := &NVDIndex{byProduct: make(map[string][]nvdMatch)}
+	for _, file := range files {
+		if err := idx.loadFile(file); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return idx, nil
+}
+
+func (idx *NVDIndex) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	// feeds run to hundreds of MB, so they are decoded straight from the
+	// file rather than read into memory first
+	var feed nvdFeed
+	if err := json.NewDecoder(r).Decode(&feed); err != nil {
+		return err
+	}
+	for _, item := range feed.Items {
+		cvss := item.Impact.V3.CVSS.BaseScore
+		if cvss == 0 {
+			cvss = item.Impact.V2.CVSS.BaseScore
+		}
+		// in the 1.1 layout every top-level node is a configuration
+		var configs []*nvdCond
+		for _, n := range item.Configurations.Nodes {
+			configs = append(configs, buildCond(n))
+		}
+		idx.add(item.CVE.Meta.ID, cvss, configs)
+	}
+
+	for _, v := range feed.Vulnerabilities {
+		m := v.CVE.Metrics
+		var cvss float64
+		switch {
+		case len(m.V31) > 0:
+			cvss = m.V31[0].Data.BaseScore
+		case len(m.V30) > 0:
+			cvss = m.V30[0].Data.BaseScore
+		case len(m.V2) > 0:
+			cvss = m.V2[0].Data.BaseScore
+		}
+		var configs []*nvdCond
+		for _, c := range v.CVE.Configurations {
+			// nodes of a 2.0 configuration are joined by its operator,
+			// OR when it has none
+			root := &nvdCond{And: strings.EqualFold(c.Operator, "AND"), Negate: c.Negate}
+			for _, n := range c.Nodes {
+				root.Children = append(root.Children, buildCond(n))
+			}
+			configs = append(configs, root)
+		}
+		idx.add(v.CVE.ID, cvss, configs)
+	}
+	return nil
+}
+
+// buildCond converts a feed node; nodes without an operator are OR
+func buildCond(n nvdNode) *nvdCond {
+	c := &nvdCond{And: strings.EqualFold(n.Operator, "AND"), Negate: n.Negate}
+	for _, m := range append(n.CPEMatch11, n.CPEMatch20...) {
+		uri := m.CPE23URI
+		if uri == "" {
+			uri = m.Criteria
+		}
+		name, ok := parseCPE(uri)
+		if !ok {
+			continue
+		}
+		e := nvdCPE{Key: name.key(), Vulnerable: m.Vulnerable, Match: nvdMatch{
+			Version:        name.Version,
+			StartIncluding: m.VersionStartIncluding,
+			StartExcluding: m.VersionStartExcluding,
+			EndIncluding:   m.VersionEndIncluding,
+			EndExcluding:   m.VersionEndExcluding,
+		}}
+		c.CPEs = append(c.CPEs, e)
+	}
+	for _, child := range n.Children {
+		c.Children = append(c.Children, buildCond(child))
+	}
+	return c
+}
+
+// add indexes the vulnerable entries of one CVE's configurations
+func (idx *NVDIndex) add(id string, cvss float64, configs []*nvdCond) {
+	if id == "" {
+		return
+	}
+	added := false
+	var walk func(config, c *nvdCond)
+	walk = func(config, c *nvdCond) {
+		for _, e := range c.CPEs {
+			if !e.Vulnerable {
+				continue
+			}
+			m := e.Match
+			m.CVE, m.CVSS, m.Config = id, cvss, config
+			idx.byProduct[e.Key] = append(idx.byProduct[e.Key], m)
+			added = true
+		}
+		for _, child := range c.Children {
+			walk(config, child)
+		}
+	}
+	for _, c := range configs {
+		walk(c, c)
+	}
+	if added {
+		idx.CVEs++
+	}
+}
+
+// cpeName is the part of a CPE the matcher cares about
+type cpeName struct {
+	Vendor, Product, Version string
+}
+
+func (c cpeName) key() string {
+	return c.Vendor + ":" + c.Product
+}
+
+// parseCPE accepts both the 2.2 URI form nmap emits (cpe:/a:apache:http_server:2.4.49)
+// and the 2.3 formatted string used by NVD (cpe:2.3:a:apache:http_server:2.4.49:*:...)
+func parseCPE(s string) (cpeName, bool) {
+	var fields []string
+	switch {
+	case strings.HasPrefix(s, "cpe:2.3:"):
+		fields = strings.Split(strings.TrimPrefix(s, "cpe:2.3:"), ":")
+	case strings.HasPrefix(s, "cpe:/"):
+		fields = strings.Split(strings.TrimPrefix(s, "cpe:/"), ":")
+	default:
+		return cpeName{}, false
+	}
+	if len(fields) < 3 || fields[1] == "" || fields[2] == "" {
+		return cpeName{}, false
+	}
+	c := cpeName{Vendor: strings.ToLower(fields[1]), Product: strings.ToLower(fields[2])}
+	if len(fields) > 3 {
+		c.Version = strings.ToLower(fields[3])
+	}
+	return c, true
+}
+
+// matches reports whether version falls in the entry's exact version or range
+func (m nvdMatch) matches(version string) bool {
+	if m.Version != "" && m.Version != "*" && m.Version != "-" {
+		return compareVersions(nvdVersion(version, m.Version), m.Version) == 0
+	}
+	if m.Version == "-" {
+		return false
+	}
+	// "*" without bounds means every version is affected
+	for _, c := range []versionConstraint{
+		{">=", m.StartIncluding}, {">", m.StartExcluding},
+		{"<=", m.EndIncluding}, {"<", m.EndExcluding},
+	} {
+		if c.Version != "" && !versionSatisfies(nvdVersion(version, c.Version), []versionConstraint{c}) {
+			return false
+		}
+	}
+	return true
+}
+
+// nvdVersion drops the patch or distro suffix of a service version, so
+// "7.4p1" compares as "7.4", when the NVD version it is compared with is a
+// plain release number. NVD keeps such suffixes in the CPE update field.
+func nvdVersion(version, nvd string) string {
+	for _, part := range versionParts(nvd) {
+		if part[0] < '0' || part[0] > '9' {
+			return version
+		}
+	}
+	end := strings.IndexFunc(version, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if end <= 0 {
+		return version
+	}
+	return strings.TrimRight(version[:end], ".")
+}
+
+// matchesHost is matches for configuration entries, where a host CPE may
+// carry no version. Entries that do not constrain the version ("-", or "*"
+// without bounds, as used for platforms) match any version.
+func (m nvdMatch) matchesHost(version string) bool {
+	unbounded := m.StartIncluding == "" && m.StartExcluding == "" && m.EndIncluding == "" && m.EndExcluding == ""
+	if m.Version == "-" || ((m.Version == "" || m.Version == "*") && unbounded) {
+		return true
+	}
+	if version == "" {
+		return false
+	}
+	return m.matches(version)
+}
+
+// portCPEs returns the port's service CPEs. The version comes from the CPE
+// when nmap included one, otherwise from the service version (first word,
+// e.g. "7.4p1" from "7.4p1 Debian 10").
+func portCPEs(p Port) []cpeName {
+	var out []cpeName
+	for _, raw := range p.Service.CPEs {
+		c, ok := parseCPE(raw)
+		if !ok {
+			continue
+		}
+		if c.Version == "" {
+			c.Version = firstWord(p.Service.Version)
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// hostCPEs collects the CPEs known for a host: every service CPE and the
+// best OS match. Configurations are evaluated against this set.
+func hostCPEs(h Host) []cpeName {
+	var out []cpeName
+	for _, p := range h.Ports.Ports {
+		out = append(out, portCPEs(p)...)
+	}
+	if len(h.OS.Matches) > 0 {
+		for _, class := range h.OS.Matches[0].Classes {
+			for _, raw := range class.CPEs {
+				if c, ok := parseCPE(raw); ok {
+					out = append(out, c)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// Match returns the CVEs affecting a port, based on its service CPEs. A CVE
+// is only reported when its whole configuration holds for the host CPEs,
+// so an app vulnerable only on Windows is skipped on a Linux host, and
+// when the host's platform is unknown.
+func (idx *NVDIndex) Match(p Port, host []cpeName) []CVE {
+	seen := make(map[string]bool)
+	var out []CVE
+	for _, c := range portCPEs(p) {
+		if c.Version == "" {
+			continue // without a version every CVE for the product would match
+		}
+		for _, m := range idx.byProduct[c.key()] {
+			if seen[m.CVE] || !m.matches(c.Version) || !m.Config.eval(host) {
+				continue
+			}
+			seen[m.CVE] = true
+			out = append(out, CVE{ID: m.CVE, CVSS: m.CVSS, Source: "nvd", Potential: true})
+		}
+	}
+	return out
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testNVDFeed has a plain range match (1.1 layout) and an app that is only
+// vulnerable on Windows (2.0 layout, AND configuration)
+const (
+	testNVDFeed11 = `{"CVE_Items":[{"cve":{"CVE_data_meta":{"ID":"CVE-2023-38408"}},
+  "configurations":{"nodes":[{"operator":"OR","cpe_match":[{"vulnerable":true,
+    "cpe23Uri":"cpe:2.3:a:openbsd:openssh:*:*:*:*:*:*:*:*","versionEndExcluding":"9.3"}]}]},
+  "impact":{"baseMetricV3":{"cvssV3":{"baseScore":9.8}}}}]}`
+	testNVDFeed20 = `{"vulnerabilities":[{"cve":{"id":"CVE-2020-0001",
+  "metrics":{"cvssMetricV31":[{"cvssData":{"baseScore":8.1}}]},
+  "configurations":[{"operator":"AND","nodes":[
+    {"operator":"OR","cpeMatch":[{"vulnerable":true,"criteria":"cpe:2.3:a:apache:tomcat:*:*:*:*:*:*:*:*",
+      "versionStartIncluding":"9.0.0","versionEndExcluding":"9.0.31"}]},
+    {"operator":"OR","cpeMatch":[{"vulnerable":false,"criteria":"cpe:2.3:o:microsoft:windows:-:*:*:*:*:*:*:*"}]}]}]}}]}`
+	// NVD keeps OpenSSH patch levels in the update field: 7.4 here, where
+	// nmap reports 7.4p1
+	testNVDFeedSuffix = `{"CVE_Items":[
+  {"cve":{"CVE_data_meta":{"ID":"CVE-2016-10009"}},
+   "configurations":{"nodes":[{"operator":"OR","cpe_match":[{"vulnerable":true,
+     "cpe23Uri":"cpe:2.3:a:openbsd:openssh:7.4:-:*:*:*:*:*:*"}]}]},
+   "impact":{"baseMetricV3":{"cvssV3":{"baseScore":7.3}}}},
+  {"cve":{"CVE_data_meta":{"ID":"CVE-2016-10012"}},
+   "configurations":{"nodes":[{"operator":"OR","cpe_match":[{"vulnerable":true,
+     "cpe23Uri":"cpe:2.3:a:openbsd:openssh:*:*:*:*:*:*:*:*","versionEndIncluding":"7.4"}]}]},
+   "impact":{"baseMetricV3":{"cvssV3":{"baseScore":7.8}}}},
+  {"cve":{"CVE_data_meta":{"ID":"CVE-2000-0001"}},
+   "configurations":{"nodes":[{"operator":"OR","cpe_match":[{"vulnerable":true,
+     "cpe23Uri":"cpe:2.3:a:openbsd:openssh:*:*:*:*:*:*:*:*","versionEndExcluding":"7.4p1"}]}]},
+   "impact":{"baseMetricV3":{"cvssV3":{"baseScore":7.0}}}}]}`
+)
+
+func TestNVDIndexMatch(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.json"), []byte(testNVDFeed11), 0o600)
+	os.WriteFile(filepath.Join(dir, "b.json"), []byte(testNVDFeed20), 0o600)
+	gz, err := os.Create(filepath.Join(dir, "c.json.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := gzip.NewWriter(gz)
+	zw.Write([]byte(testNVDFeedSuffix))
+	zw.Close()
+	gz.Close()
+
+	idx, err := loadNVDIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx.CVEs != 5 {
+		t.Errorf("indexed %d CVEs, want 5", idx.CVEs)
+	}
+
+	ssh := testPort(22, "ssh")
+	ssh.Service.CPEs = []string{"cpe:/a:openbsd:openssh:7.4"}
+	sshPatched := testPort(22, "ssh")
+	sshPatched.Service.CPEs = []string{"cpe:/a:openbsd:openssh:7.4p1"}
+	sshDistro := testPort(22, "ssh")
+	sshDistro.Service.CPEs = []string{"cpe:/a:openbsd:openssh"}
+	sshDistro.Service.Version = "7.4p1 Debian 10+deb9u7"
+	sshNew := testPort(22, "ssh")
+	sshNew.Service.CPEs = []string{"cpe:/a:openbsd:openssh"}
+	sshNew.Service.Version = "9.6p1 Ubuntu 3"
+	tomcat := testPort(8080, "http")
+	tomcat.Service.CPEs = []string{"cpe:/a:apache:tomcat:9.0.30"}
+	noVersion := testPort(8080, "http")
+	noVersion.Service.CPEs = []string{"cpe:/a:apache:tomcat"}
+
+	windows := cpeName{Vendor: "microsoft", Product: "windows"}
+	linux := cpeName{Vendor: "linux", Product: "linux_kernel"}
+	tests := []struct {
+		name string
+		port Port
+		os   []cpeName
+		want []string
+	}{
+		{"version in range", ssh, nil, []string{"CVE-2023-38408", "CVE-2016-10009", "CVE-2016-10012", "CVE-2000-0001"}},
+		{"patch suffix", sshPatched, nil, []string{"CVE-2023-38408", "CVE-2016-10009", "CVE-2016-10012"}},
+		{"distro suffix", sshDistro, nil, []string{"CVE-2023-38408", "CVE-2016-10009", "CVE-2016-10012"}},
+		{"service version above range", sshNew, nil, nil},
+		{"platform matches", tomcat, []cpeName{windows}, []string{"CVE-2020-0001"}},
+		{"other platform", tomcat, []cpeName{linux}, nil},
+		{"unknown platform", tomcat, nil, nil},
+		{"no version", noVersion, []cpeName{windows}, nil},
+	}
+	for _, tt := range tests {
+		host := append(portCPEs(tt.port), tt.os...)
+		var got []string
+		for _, c := range idx.Match(tt.port, host) {
+			if !c.Potential || c.Source != "nvd" {
+				t.Errorf("%s: %s not marked as a potential nvd match", tt.name, c.ID)
+			}
+			got = append(got, c.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseCPE(t *testing.T) {
+	tests := []struct {
+		in   string
+		want cpeName
+		ok   bool
+	}{
+		{"cpe:/a:apache:http_server:2.4.49", cpeName{"apache", "http_server", "2.4.49"}, true},
+		{"cpe:2.3:a:OpenBSD:OpenSSH:7.4:*:*:*:*:*:*:*", cpeName{"openbsd", "openssh", "7.4"}, true},
+		{"cpe:/o:linux:linux_kernel", cpeName{"linux", "linux_kernel", ""}, true},
+		{"cpe:/a:apache", cpeName{}, false},
+		{"apache httpd", cpeName{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseCPE(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseCPE(%q) = %+v, %v; want %+v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PortSpec identifies a port, optionally restricted to a protocol ("" matches any)
+type PortSpec struct {
+	Port     int
+	Protocol string
+}
+
+// Matches reports whether the spec covers the given port
+func (s PortSpec) Matches(p Port) bool {
+	if s.Port != p.PortId {
+		return false
+	}
+	return s.Protocol == "" || strings.EqualFold(s.Protocol, p.Protocol)
+}
+
+func (s PortSpec) String() string {
+	if s.Protocol == "" {
+		return strconv.Itoa(s.Port)
+	}
+	return fmt.Sprintf("%d/%s", s.Port, s.Protocol)
+}
+
+// parsePortSpecs parses a comma separated list such as "22,443/tcp,53/udp"
+func parsePortSpecs(s string) ([]PortSpec, error) {
+	var specs []PortSpec
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		num, proto, _ := strings.Cut(field, "/")
+		port, err := strconv.Atoi(num)
+		if err != nil || port < 0 || port > 65535 {
+			return nil, fmt.Errorf("invalid port %q", field)
+		}
+		specs = append(specs, PortSpec{Port: port, Protocol: strings.ToLower(proto)})
+	}
+	return specs, nil
+}
+
+// splitList splits a comma separated flag value, dropping empty entries
+func splitList(s string) []string {
+	var out []string
+	for _, field := range strings.Split(s, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			out = append(out, field)
+		}
+	}
+	return out
+}
+
+// ExposurePolicy describes what a scanned host is allowed to expose
+type ExposurePolicy struct {
+	DeniedServices []string   // service names that must never be open
+	AllowedPorts   []PortSpec // if set, the only ports that may be open
+}
+
+// PolicyCheck is the outcome of one policy rule against one host
+type PolicyCheck struct {
+	Name       string
+	Violations []Port
+}
+
+// Passed reports whether the host satisfied the check
+func (c PolicyCheck) Passed() bool {
+	return len(c.Violations) == 0
+}
+
+// Evaluate runs every rule of the policy against the host's open ports
+func (p ExposurePolicy) Evaluate(h Host) []PolicyCheck {
+	open := h.OpenPorts()
+	var checks []PolicyCheck
+
+	for _, svc := range p.DeniedServices {
+		check := PolicyCheck{Name: "no " + svc}
+		for _, port := range open {
+			if strings.EqualFold(port.Service.Name, svc) {
+				check.Violations = append(check.Violations, port)
+			}
+		}
+		checks = append(checks, check)
+	}
+
+	if len(p.AllowedPorts) > 0 {
+		names := make([]string, len(p.AllowedPorts))
+		for i, spec := range p.AllowedPorts {
+			names[i] = spec.String()
+		}
+		check := PolicyCheck{Name: "only " + strings.Join(names, ", ") + " open"}
+		for _, port := range open {
+			allowed := false
+			for _, spec := range p.AllowedPorts {
+				if spec.Matches(port) {
+					allowed = true
+					break
+				}
+			}
+			if !allowed {
+				check.Violations = append(check.Violations, port)
+			}
+		}
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+// Summary renders a one-line description of the port, e.g. "23/tcp open telnet (Linux telnetd)"
+func (p Port) Summary() string {
+	s := fmt.Sprintf("%d/%s %s", p.PortId, p.Protocol, p.State.State)
+	if p.Service.Name != "" {
+		s += " " + p.Service.Name
+	}
+	if product := strings.TrimSpace(p.Service.Product + " " + p.Service.Version); product != "" {
+		s += " (" + product + ")"
+	}
+	return s
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/netip"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Redactor pseudonymizes addresses and names so reports can be shared.
+// Mappings are keyed by a random per-run secret and are consistent within
+// the run: the same input always gives the same pseudonym, and IP addresses
+// are prefix-preserving, so hosts sharing a subnet still share one.
+type Redactor struct {
+	key       []byte
+	ips       map[string]string
+	hostnames map[string]string
+	macs      map[string]string
+
+	namesRe *regexp.Regexp // known hostnames, longest first; nil when stale
+}
+
+// redactor is active with -redact, nil otherwise. Every method is nil-safe.
+var redactor *Redactor
+
+func newRedactor() (*Redactor, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return &Redactor{
+		key:       key,
+		ips:       make(map[string]string),
+		hostnames: make(map[string]string),
+		macs:      make(map[string]string),
+	}, nil
+}
+
+func (r *Redactor) mac(data string) []byte {
+	m := hmac.New(sha256.New, r.key)
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
+
+// Addr maps an IP address bit by bit: each output bit is the input bit
+// flipped by a keyed function of the bits before it (the Crypto-PAn scheme),
+// so two addresses sharing an n-bit prefix map to addresses sharing one too.
+// Loopback and unspecified addresses are kept.
+func (r *Redactor) Addr(a netip.Addr) netip.Addr {
+	a = a.Unmap()
+	if a.IsLoopback() || a.IsUnspecified() {
+		return a
+	}
+	if s, ok := r.ips[a.String()]; ok {
+		return netip.MustParseAddr(s)
+	}
+	in := a.AsSlice()
+	out := make([]byte, len(in))
+	prefix := make([]byte, len(in))
+	for i := 0; i < len(in)*8; i++ {
+		byteIdx, bit := i/8, byte(0x80>>(i%8))
+		flip := r.mac(string(append([]byte{byte(i)}, prefix...)))[0] & 1
+		v := in[byteIdx] & bit
+		if flip == 1 {
+			v ^= bit
+		}
+		out[byteIdx] |= v
+		prefix[byteIdx] |= in[byteIdx] & bit
+	}
+	pseudo, _ := netip.AddrFromSlice(out)
+	r.ips[a.String()] = pseudo.String()
+	return pseudo
+}
+
+// IP pseudonymizes an address string, returning it unchanged when it does not parse
+func (r *Redactor) IP(s string) string {
+	if r == nil {
+		return s
+	}
+	a, err := netip.ParseAddr(s)
+	if err != nil {
+		return s
+	}
+	return r.Addr(a).String()
+}
+
+// Prefix maps a network onto the network its addresses map into
+func (r *Redactor) Prefix(p netip.Prefix) netip.Prefix {
+	if r == nil {
+		return p
+	}
+	masked, _ := r.Addr(p.Addr()).Prefix(p.Bits())
+	return masked
+}
+
+// Hostname maps each DNS label, keyed by the label and everything to its
+// right, so names under one domain still share a pseudonymous domain. The
+// top-level label is kept.
+func (r *Redactor) Hostname(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	key := strings.ToLower(strings.TrimSuffix(s, "."))
+	if v, ok := r.hostnames[key]; ok {
+		return v
+	}
+	labels := strings.Split(key, ".")
+	out := make([]string, len(labels))
+	for i := range labels {
+		if i == len(labels)-1 && i > 0 {
+			out[i] = labels[i]
+			continue
+		}
+		out[i] = "n" + hex.EncodeToString(r.mac("host:" + strings.Join(labels[i:], ".")))[:6]
+	}
+	v := strings.Join(out, ".")
+	r.hostnames[key] = v
+	r.namesRe = nil
+	// register the parent domains too, so they are replaced in free text
+	for i := 1; i < len(labels)-1; i++ {
+		r.Hostname(strings.Join(labels[i:], "."))
+	}
+	return v
+}
+
+// MAC keeps the vendor prefix (OUI) and maps the device part
+func (r *Redactor) MAC(s string) string {
+	if r == nil {
+		return s
+	}
+	hw, err := net.ParseMAC(s)
+	if err != nil || len(hw) != 6 {
+		return s
+	}
+	key := strings.ToLower(hw.String())
+	if v, ok := r.macs[key]; ok {
+		return v
+	}
+	sum := r.mac("mac:" + key)
+	out := net.HardwareAddr{hw[0], hw[1], hw[2], sum[0], sum[1], sum[2]}
+	v := strings.ToUpper(out.String())
+	r.macs[key] = v
+	return v
+}
+
+var (
+	ipv4Text   = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}(?:/\d{1,2})?\b`)
+	colonText  = regexp.MustCompile(`[0-9A-Fa-f]*(?::[0-9A-Fa-f]*){2,}(?:/\d{1,3})?`)
+	dashMAC    = regexp.MustCompile(`\b[0-9A-Fa-f]{2}(?:-[0-9A-Fa-f]{2}){5}\b`)
+	colonMAC   = regexp.MustCompile(`^[0-9A-Fa-f]{2}(?::[0-9A-Fa-f]{2}){5}$`)
+	hostnameRe = regexp.MustCompile(`^[A-Za-z0-9_-]+(?:\.[A-Za-z0-9_-]+)*$`)
+)
+
+// redactAddrToken maps "addr" or "addr/bits"; ok is false when it is neither
+func (r *Redactor) redactAddrToken(tok string) (string, bool) {
+	addr, _, hasBits := strings.Cut(tok, "/")
+	a, err := netip.ParseAddr(addr)
+	if err != nil {
+		return tok, false
+	}
+	if !hasBits {
+		return r.Addr(a).String(), true
+	}
+	p, err := netip.ParsePrefix(tok)
+	if err != nil {
+		return tok, false
+	}
+	return r.Prefix(p).String(), true
+}
+
+// Text replaces every IP address, MAC address and known hostname in free text
+func (r *Redactor) Text(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	s = replaceIndexed(s, ipv4Text, func(m string, next string) string {
+		// a fifth dotted number means a version string, not an address
+		if len(next) > 1 && next[0] == '.' && next[1] >= '0' && next[1] <= '9' {
+			return m
+		}
+		v, _ := r.redactAddrToken(m)
+		return v
+	})
+	s = colonText.ReplaceAllStringFunc(s, func(m string) string {
+		if colonMAC.MatchString(m) {
+			return r.MAC(m)
+		}
+		// SSH fingerprints and clock times do not parse as addresses
+		v, _ := r.redactAddrToken(m)
+		return v
+	})
+	s = dashMAC.ReplaceAllStringFunc(s, r.MAC)
+	if re := r.knownNames(); re != nil {
+		s = replaceIndexed(s, re, func(m string, next string) string {
+			if next != "" && isHostChar(next[0]) && next[0] != '.' {
+				return m
+			}
+			return r.Hostname(m)
+		})
+	}
+	return s
+}
+
+// knownNames is a regexp matching every registered hostname
+func (r *Redactor) knownNames() *regexp.Regexp {
+	if r.namesRe != nil || len(r.hostnames) == 0 {
+		return r.namesRe
+	}
+	names := make([]string, 0, len(r.hostnames))
+	for n := range r.hostnames {
+		names = append(names, regexp.QuoteMeta(n))
+	}
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	r.namesRe = regexp.MustCompile(`(?i)\b(?:` + strings.Join(names, "|") + `)`)
+	return r.namesRe
+}
+
+func isHostChar(c byte) bool {
+	return c == '-' || c == '_' || c == '.' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// replaceIndexed is ReplaceAllStringFunc with the text following each match,
+// since Go regexps cannot look ahead
+func replaceIndexed(s string, re *regexp.Regexp, fn func(match, next string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringIndex(s, -1) {
+		b.WriteString(s[last:loc[0]])
+		b.WriteString(fn(s[loc[0]:loc[1]], s[loc[1]:]))
+		last = loc[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// registerName adds a hostname seen in the data, ignoring anything that is
+// not a plain DNS name
+func (r *Redactor) registerName(s string) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "*.")
+	s = strings.TrimSuffix(s, `\x00`)
+	if s == "" || !hostnameRe.MatchString(s) || strings.Trim(s, "0123456789.") == "" {
+		return
+	}
+	if _, err := netip.ParseAddr(s); err == nil {
+		return
+	}
+	r.Hostname(s)
+}
+
+// Host pseudonymizes a decoded host in place, before any analysis, so every
+// derived field and output sees only pseudonyms. Names the scripts reveal
+// (SMB computer and domain names, certificate names) are registered first so
+// they are replaced wherever they appear.
+func (r *Redactor) Host(h *Host) {
+	if r == nil {
+		return
+	}
+	for _, n := range h.Hostnames.Names {
+		r.registerName(n.Name)
+	}
+	if w := analyzeWindows(*h); w != nil {
+		for _, n := range []string{w.FQDN, w.Domain, w.Forest, w.ComputerName, w.NetBIOSName, w.Workgroup} {
+			r.registerName(n)
+		}
+	}
+	for _, p := range h.Ports.Ports {
+		if t := analyzeTLS(p, time.Now()); t != nil && t.Cert != nil {
+			r.registerName(t.Cert.Subject)
+			for _, san := range t.Cert.SANs {
+				r.registerName(strings.TrimPrefix(san, "DNS:"))
+			}
+		}
+	}
+
+	for i, a := range h.Addresses {
+		if a.AddrType == "mac" {
+			h.Addresses[i].Addr = r.MAC(a.Addr)
+		} else {
+			h.Addresses[i].Addr = r.IP(a.Addr)
+		}
+	}
+	for i, n := range h.Hostnames.Names {
+		h.Hostnames.Names[i].Name = r.Hostname(n.Name)
+	}
+	for i := range h.Ports.Ports {
+		p := &h.Ports.Ports[i]
+		p.Service.Extras = r.Text(p.Service.Extras)
+		r.scripts(p.Scripts)
+	}
+	r.scripts(h.HostScripts)
+}
+
+func (r *Redactor) scripts(scripts []Script) {
+	for i := range scripts {
+		s := &scripts[i]
+		s.Output = r.Text(s.Output)
+		r.tables(s.Tables)
+		r.elems(s.Elems)
+	}
+}
+
+func (r *Redactor) tables(tables []ScriptTable) {
+	for i := range tables {
+		t := &tables[i]
+		t.Key = r.Text(t.Key)
+		r.tables(t.Tables)
+		r.elems(t.Elems)
+	}
+}
+
+func (r *Redactor) elems(elems []ScriptElem) {
+	for i := range elems {
+		elems[i].Value = r.Text(elems[i].Value)
+	}
+}
+
+// argFileExts are extensions of files named on the nmap command line, which
+// look like hostnames but are not
+var argFileExts = []string{"xml", "txt", "nmap", "gnmap", "html", "htm", "json", "csv", "lst", "list", "log", "nse", "xsl"}
+
+// RunInfo pseudonymizes the command line, which usually lists the targets
+func (r *Redactor) RunInfo(info *NmapRunInfo) {
+	if r == nil {
+		return
+	}
+	for _, tok := range strings.Fields(info.Args) {
+		if strings.HasPrefix(tok, "-") || !strings.Contains(tok, ".") || !hostnameRe.MatchString(tok) {
+			continue
+		}
+		labels := strings.Split(tok, ".")
+		tld := strings.ToLower(labels[len(labels)-1])
+		if strings.Trim(tld, "abcdefghijklmnopqrstuvwxyz") != "" || containsString(argFileExts, tld) {
+			continue
+		}
+		r.registerName(tok)
+	}
+	info.Args = r.Text(info.Args)
+}
+
+// Inventory re-keys the -assets records by pseudonymized address and name
+func (r *Redactor) Inventory(inv *AssetInventory) {
+	if r == nil || inv == nil {
+		return
+	}
+	byIP := make(map[string]*Asset)
+	for k, a := range inv.byIP {
+		if _, err := net.ParseMAC(k); err == nil {
+			a.IP = r.MAC(k)
+		} else {
+			a.IP = r.IP(k)
+		}
+		byIP[canonicalIP(a.IP)] = a
+	}
+	byName := make(map[string]*Asset)
+	for k, a := range inv.byName {
+		a.Hostname = r.Hostname(k)
+		byName[a.Hostname] = a
+	}
+	inv.byIP, inv.byName = byIP, byName
+}
+
+// Segments maps the -segments networks
+func (r *Redactor) Segments(segments []Segment) {
+	for i := range segments {
+		segments[i].Prefix = r.Prefix(segments[i].Prefix)
+	}
+}
+
+// Baseline maps the -compliance networks and plain hostnames; glob patterns
+// cannot be mapped and will not match pseudonymized names
+func (r *Redactor) Baseline(b *ComplianceBaseline) {
+	if r == nil || b == nil {
+		return
+	}
+	for gi := range b.Groups {
+		g := &b.Groups[gi]
+		for i, n := range g.nets {
+			a, _ := netip.AddrFromSlice(n.IP)
+			bits, _ := n.Mask.Size()
+			p := r.Prefix(netip.PrefixFrom(a.Unmap(), bits))
+			g.nets[i] = &net.IPNet{IP: p.Addr().AsSlice(), Mask: n.Mask}
+		}
+		for i, name := range g.names {
+			if !strings.ContainsAny(name, "*?[") {
+				g.names[i] = r.Hostname(name)
+			}
+		}
+	}
+}
+
+// WriteMap saves the pseudonym to original mapping; the file is private
+func (r *Redactor) WriteMap(filename string) error {
+	if r == nil || filename == "" {
+		return nil
+	}
+	invert := func(m map[string]string) map[string]string {
+		out := make(map[string]string, len(m))
+		for orig, pseudo := range m {
+			out[pseudo] = orig
+		}
+		return out
+	}
+	b, err := json.MarshalIndent(map[string]map[string]string{
+		"ips":       invert(r.ips),
+		"hostnames": invert(r.hostnames),
+		"macs":      invert(r.macs),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(b, '\n'), 0o600)
+}
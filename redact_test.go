@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedactorAddrPreservesPrefixes(t *testing.T) {
+	r, err := newRedactor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		a, b   string
+		common int // bits the two addresses share
+	}{
+		{"10.1.2.3", "10.1.2.200", 24},
+		{"10.1.2.3", "10.1.3.3", 23},
+		{"192.168.0.1", "10.0.0.1", 0},
+		{"2001:db8::1", "2001:db8::ffff", 112},
+	}
+	for _, tt := range tests {
+		a, b := r.Addr(netip.MustParseAddr(tt.a)), r.Addr(netip.MustParseAddr(tt.b))
+		if got := commonPrefixBits(a, b); got != tt.common {
+			t.Errorf("%s -> %s, %s -> %s share %d bits, want %d", tt.a, a, tt.b, b, got, tt.common)
+		}
+		if a.String() == tt.a {
+			t.Errorf("%s not redacted", tt.a)
+		}
+	}
+
+	// networks map onto the network of their addresses
+	p := r.Prefix(netip.MustParsePrefix("10.1.2.0/24"))
+	if !p.Contains(r.Addr(netip.MustParseAddr("10.1.2.77"))) {
+		t.Errorf("redacted 10.1.2.77 is outside redacted 10.1.2.0/24 (%s)", p)
+	}
+	if got := r.IP("127.0.0.1"); got != "127.0.0.1" {
+		t.Errorf("loopback redacted to %s", got)
+	}
+}
+
+// commonPrefixBits counts the leading bits two addresses of one family share
+func commonPrefixBits(a, b netip.Addr) int {
+	x, y := a.AsSlice(), b.AsSlice()
+	for i := range x {
+		for bit := 0; bit < 8; bit++ {
+			mask := byte(0x80 >> bit)
+			if x[i]&mask != y[i]&mask {
+				return i*8 + bit
+			}
+		}
+	}
+	return len(x) * 8
+}
+
+func TestRedactorConsistent(t *testing.T) {
+	r, err := newRedactor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.IP("10.0.0.5") != r.IP("10.0.0.5") {
+		t.Error("same address redacted differently")
+	}
+	web, db := r.Hostname("web.corp.example.com"), r.Hostname("DB.corp.example.com.")
+	if web == "web.corp.example.com" || !strings.HasSuffix(web, ".com") {
+		t.Errorf("hostname redacted to %q", web)
+	}
+	// names under one domain share the pseudonymous domain
+	if _, wd, _ := strings.Cut(web, "."); !strings.HasSuffix(db, "."+wd) {
+		t.Errorf("%q and %q do not share a domain", web, db)
+	}
+	if r.Hostname("WEB.corp.example.com") != web {
+		t.Error("hostname case changed the pseudonym")
+	}
+	if mac := r.MAC("00:0c:29:aa:bb:cc"); !strings.HasPrefix(mac, "00:0C:29:") || mac == "00:0C:29:AA:BB:CC" {
+		t.Errorf("MAC redacted to %s, want the vendor prefix kept", mac)
+	}
+
+	// free text uses the same pseudonyms as the fields
+	text := r.Text("Connected to web.corp.example.com (10.0.0.5), gateway 10.0.0.1/24, build 10.0.17763.1.0")
+	for _, want := range []string{web, r.IP("10.0.0.5"), r.Prefix(netip.MustParsePrefix("10.0.0.1/24")).String()} {
+		if !strings.Contains(text, want) {
+			t.Errorf("%q does not contain %q", text, want)
+		}
+	}
+	if !strings.Contains(text, "10.0.17763.1.0") {
+		t.Errorf("version string redacted: %q", text)
+	}
+
+	// a second redactor uses a new key
+	other, _ := newRedactor()
+	if other.IP("10.0.0.5") == r.IP("10.0.0.5") {
+		t.Error("two runs produced the same pseudonym")
+	}
+}
+
+func TestRedactorHostAndMap(t *testing.T) {
+	r, err := newRedactor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := testHost("10.0.0.5", "up", testPort(443, "https"))
+	h.Hostnames.Names = []Hostname{{Name: "web.corp.example.com"}}
+	h.Ports.Ports[0].Scripts = []Script{{ID: "ssl-cert", Output: "Subject: commonName=web.corp.example.com\nSubject Alternative Name: DNS:web.corp.example.com"}}
+	r.Host(&h)
+
+	addr, name := h.PrimaryAddr(), h.Hostnames.Names[0].Name
+	if addr == "10.0.0.5" || name == "web.corp.example.com" {
+		t.Fatalf("host not redacted: %s %s", addr, name)
+	}
+	if out := h.Ports.Ports[0].Scripts[0].Output; strings.Contains(out, "corp") || !strings.Contains(out, name) {
+		t.Errorf("script output = %q", out)
+	}
+
+	file := filepath.Join(t.TempDir(), "map.json")
+	if err := r.WriteMap(file); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]map[string]string
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["ips"][addr] != "10.0.0.5" || m["hostnames"][name] != "web.corp.example.com" {
+		t.Errorf("map = %v", m)
+	}
+	if fi, _ := os.Stat(file); fi.Mode().Perm() != 0o600 {
+		t.Errorf("map mode = %v, want 0600", fi.Mode().Perm())
+	}
+}
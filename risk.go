@@ -0,0 +1,491 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Service categories, kept in step with the lists in the embedded report script
+var (
+	webServices      = []string{"http", "https", "nginx", "apache", "iis"}
+	databaseServices = []string{"mysql", "postgresql", "mongodb", "redis", "oracle"}
+	criticalServices = []string{"telnet", "rlogin", "rsh"}
+	windowsServices  = []string{"msrpc", "netbios-ssn", "microsoft-ds", "rdp"}
+)
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceMatches reports whether a service name contains any of the category
+// entries, the same substring test the report's filter chips use
+func serviceMatches(service string, category []string) bool {
+	service = strings.ToLower(service)
+	for _, c := range category {
+		if strings.Contains(service, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// Risk levels, lowest to highest
+const (
+	RiskInfo     = "info"
+	RiskLow      = "low"
+	RiskMedium   = "medium"
+	RiskHigh     = "high"
+	RiskCritical = "critical"
+)
+
+// riskRank orders levels so they can be compared
+func riskRank(level string) int {
+	switch level {
+	case RiskCritical:
+		return 4
+	case RiskHigh:
+		return 3
+	case RiskMedium:
+		return 2
+	case RiskLow:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// riskLevel maps a 0-100 score onto a level
+func riskLevel(score int) string {
+	switch {
+	case score >= 50:
+		return RiskCritical
+	case score >= 30:
+		return RiskHigh
+	case score >= 15:
+		return RiskMedium
+	case score > 0:
+		return RiskLow
+	default:
+		return RiskInfo
+	}
+}
+
+// Finding is one matched risk rule on a port
+type Finding struct {
+	RuleID      string `json:"rule_id"`
+	Title       string `json:"title"`
+	Level       string `json:"level"`
+	Score       int    `json:"score"`
+	Description string `json:"description,omitempty"`
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// PortRisk is the scoring result attached to each Port
+type PortRisk struct {
+	Score    int       `json:"score"`
+	Level    string    `json:"level"`
+	Findings []Finding `json:"findings,omitempty"`
+}
+
+// Badge is the upper-case label shown in the report, e.g. "CRITICAL"
+func (r PortRisk) Badge() string {
+	return strings.ToUpper(r.Level)
+}
+
+// HostRisk rolls up the port scores: the score is the capped sum, weighted
+// by the asset's business criticality, and the level is that of the worst port
+type HostRisk struct {
+	Score       int    `json:"score"`
+	Level       string `json:"level"`
+	Findings    int    `json:"findings"`
+	Criticality string `json:"criticality,omitempty"` // from -assets, when it weighted the score
+}
+
+// Badge is the upper-case label shown in the report, e.g. "CRITICAL"
+func (r HostRisk) Badge() string {
+	return strings.ToUpper(r.Level)
+}
+
+// RiskRule matches a port and contributes its finding when it does.
+// MatchHost, when set, also checks the host itself (its host scripts).
+type RiskRule struct {
+	Finding
+	Match     func(p Port) bool
+	MatchHost func(h Host) bool
+}
+
+// hostRuleFindings evaluates the host-level rules
+func hostRuleFindings(h Host, rules []RiskRule) []Finding {
+	var out []Finding
+	for _, r := range rules {
+		if r.MatchHost != nil && r.MatchHost(h) {
+			out = append(out, r.Finding)
+		}
+	}
+	return out
+}
+
+// scriptFlagsVulnerable reports whether an NSE script reported a confirmed
+// vulnerability ("State: VULNERABLE", but not "NOT VULNERABLE")
+func scriptFlagsVulnerable(s Script) bool {
+	out := strings.ToUpper(s.Output)
+	return strings.Contains(out, "VULNERABLE") && !strings.Contains(out, "NOT VULNERABLE")
+}
+
+func hasScript(p Port, id string) bool {
+	for _, s := range p.Scripts {
+		if s.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultRiskRules is the built-in rule set. It supersedes the report's old
+// client-side calculateRiskScore and the hard-coded telnet/ftp/http badges.
+var defaultRiskRules = []RiskRule{
+	{
+		Finding: Finding{RuleID: "cleartext-remote-shell", Title: "Cleartext remote shell", Level: RiskCritical, Score: 50,
+			Description: "Telnet, rlogin and rsh send credentials and sessions unencrypted.",
+			Remediation: "Disable the service and use SSH instead."},
+		Match: func(p Port) bool { return containsString(criticalServices, p.Service.Name) },
+	},
+	{
+		Finding: Finding{RuleID: "cleartext-ftp", Title: "Cleartext FTP", Level: RiskHigh, Score: 30,
+			Description: "FTP sends credentials and data unencrypted.",
+			Remediation: "Replace with SFTP or FTPS, or restrict access."},
+		Match: func(p Port) bool { return p.Service.Name == "ftp" },
+	},
+	{
+		Finding: Finding{RuleID: "ftp-anonymous", Title: "Anonymous FTP login allowed", Level: RiskHigh, Score: 20,
+			Remediation: "Disable anonymous access unless the server is meant to be public."},
+		Match: func(p Port) bool { return hasScript(p, "ftp-anon") },
+	},
+	{
+		Finding: Finding{RuleID: "vulnerable-script", Title: "NSE script reported a vulnerability", Level: RiskHigh, Score: 40,
+			Remediation: "Review the script output and patch the affected software."},
+		Match: func(p Port) bool {
+			for _, s := range p.Scripts {
+				if scriptFlagsVulnerable(s) {
+					return true
+				}
+			}
+			return false
+		},
+	},
+	{
+		Finding: Finding{RuleID: "cve-critical", Title: "Critical CVE reported (CVSS 9.0+)", Level: RiskCritical, Score: 50,
+			Description: "A vulnerability scan script reported a CVE with a CVSS score of 9.0 or higher.",
+			Remediation: "Patch or upgrade the affected software; see the CVE list in the port details."},
+		Match:     func(p Port) bool { return maxCVSS(p.CVEs) >= 9 },
+		MatchHost: func(h Host) bool { return maxCVSS(h.CVEs) >= 9 },
+	},
+	{
+		Finding: Finding{RuleID: "cve-high", Title: "High severity CVE reported (CVSS 7.0-8.9)", Level: RiskHigh, Score: 30,
+			Description: "A vulnerability scan script reported a CVE with a CVSS score between 7.0 and 8.9.",
+			Remediation: "Patch or upgrade the affected software; see the CVE list in the port details."},
+		Match:     func(p Port) bool { return maxCVSS(p.CVEs) >= 7 && maxCVSS(p.CVEs) < 9 },
+		MatchHost: func(h Host) bool { return maxCVSS(h.CVEs) >= 7 && maxCVSS(h.CVEs) < 9 },
+	},
+	{
+		Finding: Finding{RuleID: "cve-exploit", Title: "Public exploit available", Level: RiskHigh, Score: 20,
+			Description: "At least one reported CVE has a known public exploit.",
+			Remediation: "Prioritise patching this service."},
+		Match:     func(p Port) bool { return hasExploitableCVE(p.CVEs) },
+		MatchHost: func(h Host) bool { return hasExploitableCVE(h.CVEs) },
+	},
+	{
+		Finding: Finding{RuleID: "cve-potential", Title: "Potential CVE (version match)", Level: RiskMedium, Score: 10,
+			Description: "The service version matches a high or critical CVE in the NVD feed. The match is not confirmed by a scan script.",
+			Remediation: "Check whether the CVE applies to this installation and upgrade if it does."},
+		Match: func(p Port) bool { return maxPotentialCVSS(p.CVEs) >= 7 },
+	},
+	{
+		Finding: Finding{RuleID: "weak-tls", Title: "Weak TLS configuration", Level: RiskMedium, Score: 20,
+			Description: "The service offers deprecated protocol versions or weak ciphers.",
+			Remediation: "Disable SSLv3, TLS 1.0 and 1.1 and remove ciphers graded below A."},
+		Match: func(p Port) bool {
+			if p.TLS == nil {
+				return false
+			}
+			least := p.TLS.LeastStrength
+			return len(p.TLS.DeprecatedProtocols()) > 0 || len(p.TLS.WeakCiphers()) > 0 || (len(least) == 1 && least >= "C")
+		},
+	},
+	{
+		Finding: Finding{RuleID: "tls-cert-expired", Title: "TLS certificate expired", Level: RiskHigh, Score: 30,
+			Remediation: "Renew the certificate."},
+		Match: func(p Port) bool { return p.TLS != nil && p.TLS.Cert != nil && p.TLS.Cert.Expired() },
+	},
+	{
+		Finding: Finding{RuleID: "tls-cert-expiring", Title: "TLS certificate expires within 30 days", Level: RiskMedium, Score: 15,
+			Remediation: "Renew the certificate before it expires."},
+		Match: func(p Port) bool {
+			return p.TLS != nil && p.TLS.Cert != nil && !p.TLS.Cert.NotAfter.IsZero() && p.TLS.Cert.Days >= 0 && p.TLS.Cert.Days <= 30
+		},
+	},
+	{
+		Finding: Finding{RuleID: "tls-self-signed", Title: "Self-signed TLS certificate", Level: RiskLow, Score: 10,
+			Remediation: "Use a certificate issued by a trusted (public or internal) CA."},
+		Match: func(p Port) bool { return p.TLS != nil && p.TLS.Cert != nil && p.TLS.Cert.SelfSigned },
+	},
+	{
+		Finding: Finding{RuleID: "tls-weak-key", Title: "Weak TLS certificate key", Level: RiskMedium, Score: 15,
+			Description: "RSA keys shorter than 2048 bits or EC keys shorter than 224 bits.",
+			Remediation: "Reissue the certificate with a stronger key."},
+		Match: func(p Port) bool { return p.TLS != nil && p.TLS.Cert != nil && p.TLS.Cert.WeakKey() },
+	},
+	{
+		Finding: Finding{RuleID: "tls-weak-signature", Title: "TLS certificate signed with MD5 or SHA-1", Level: RiskMedium, Score: 15,
+			Remediation: "Reissue the certificate with a SHA-256 (or stronger) signature."},
+		Match: func(p Port) bool { return p.TLS != nil && p.TLS.Cert != nil && p.TLS.Cert.WeakSignature() },
+	},
+	{
+		Finding: Finding{RuleID: "ssh-weak-algorithms", Title: "Weak SSH algorithms offered", Level: RiskMedium, Score: 15,
+			Description: "The server offers SHA-1 or 1024-bit key exchange, CBC/RC4/64-bit block ciphers or MD5/truncated MACs.",
+			Remediation: "Restrict KexAlgorithms, Ciphers and MACs in sshd_config to modern algorithms."},
+		Match: func(p Port) bool { return p.SSH != nil && p.SSH.WeakAlgorithms() },
+	},
+	{
+		Finding: Finding{RuleID: "ssh-weak-hostkey", Title: "Weak SSH host key", Level: RiskMedium, Score: 15,
+			Remediation: "Remove DSA host keys and replace RSA keys shorter than 2048 bits; prefer Ed25519."},
+		Match: func(p Port) bool { return p.SSH != nil && p.SSH.WeakHostKey() },
+	},
+	{
+		Finding: Finding{RuleID: "http-risky-methods", Title: "Risky HTTP methods enabled", Level: RiskMedium, Score: 15,
+			Description: "PUT, DELETE, TRACE or CONNECT are accepted by the web server.",
+			Remediation: "Disable the methods the application does not need."},
+		Match: func(p Port) bool { return p.Web != nil && len(p.Web.RiskyMethods) > 0 },
+	},
+	{
+		Finding: Finding{RuleID: "http-missing-security-headers", Title: "Missing HTTP security headers", Level: RiskLow, Score: 5,
+			Description: "The response lacks HSTS, Content-Security-Policy, X-Frame-Options or X-Content-Type-Options.",
+			Remediation: "Add the missing headers in the web server or application."},
+		Match: func(p Port) bool { return p.Web != nil && len(p.Web.MissingHeaders()) > 0 },
+	},
+	{
+		Finding: Finding{RuleID: "database-exposed", Title: "Database service exposed", Level: RiskMedium, Score: 20,
+			Remediation: "Restrict database ports to application hosts."},
+		Match: func(p Port) bool { return containsString(databaseServices, p.Service.Name) },
+	},
+	{
+		Finding: Finding{RuleID: "ssh-unidentified", Title: "SSH version not identified", Level: RiskMedium, Score: 20,
+			Remediation: "Run a version scan (-sV) to confirm the SSH server is patched."},
+		Match: func(p Port) bool {
+			return p.Service.Name == "ssh" && p.Service.Product == "" && p.Service.Version == ""
+		},
+	},
+	{
+		Finding: Finding{RuleID: "web-unidentified", Title: "Unidentified web server", Level: RiskMedium, Score: 15,
+			Remediation: "Run a version scan (-sV) to identify the web server."},
+		Match: func(p Port) bool { return containsString(webServices, p.Service.Name) && p.Service.Product == "" },
+	},
+	{
+		Finding: Finding{RuleID: "smtp-exposed", Title: "Mail server exposed", Level: RiskLow, Score: 10,
+			Remediation: "Check the server is not an open relay."},
+		Match: func(p Port) bool { return p.Service.Name == "smtp" },
+	},
+}
+
+// riskRules is the active rule set applied by enrichHost
+var riskRules = defaultRiskRules
+
+// scorePort evaluates the rules against a port. Ports that are only possibly
+// open (open|filtered) count at half weight; closed and filtered ports are
+// not scored.
+func scorePort(p Port, rules []RiskRule) PortRisk {
+	risk := PortRisk{Level: RiskInfo}
+	weight := 0
+	switch p.State.State {
+	case "open":
+		weight = 2
+	case "open|filtered":
+		weight = 1
+	}
+	if weight == 0 {
+		return risk
+	}
+
+	var matched []Finding
+	for _, r := range rules {
+		if r.Match(p) {
+			matched = append(matched, r.Finding)
+		}
+	}
+	matched = append(matched, p.Detected...)
+
+	worst := RiskInfo
+	for _, f := range matched {
+		f.Score = f.Score * weight / 2
+		risk.Findings = append(risk.Findings, f)
+		risk.Score += f.Score
+		if riskRank(f.Level) > riskRank(worst) {
+			worst = f.Level
+		}
+	}
+	if risk.Score > 100 {
+		risk.Score = 100
+	}
+	// the level follows the score, but never drops below the worst finding
+	risk.Level = riskLevel(risk.Score)
+	if riskRank(worst) > riskRank(risk.Level) {
+		risk.Level = worst
+	}
+	return risk
+}
+
+// scoreHost scores every port and rolls the results up to the host, adding
+// the host-level findings (such as an end-of-life OS)
+func scoreHost(h *Host, rules []RiskRule) {
+	h.Risk = HostRisk{Level: RiskInfo}
+	for _, f := range h.Detected {
+		h.Risk.Score += f.Score
+		h.Risk.Findings++
+		if riskRank(f.Level) > riskRank(h.Risk.Level) {
+			h.Risk.Level = f.Level
+		}
+	}
+	for i := range h.Ports.Ports {
+		p := &h.Ports.Ports[i]
+		p.Risk = scorePort(*p, rules)
+		h.Risk.Score += p.Risk.Score
+		h.Risk.Findings += len(p.Risk.Findings)
+		if riskRank(p.Risk.Level) > riskRank(h.Risk.Level) {
+			h.Risk.Level = p.Risk.Level
+		}
+	}
+	if h.Asset != nil {
+		if w, ok := criticalityWeights[h.Asset.Criticality]; ok {
+			h.Risk.Score = int(math.Round(float64(h.Risk.Score) * w))
+			h.Risk.Criticality = h.Asset.Criticality
+		}
+	}
+	if h.Risk.Score > 100 {
+		h.Risk.Score = 100
+	}
+}
+
+// ReportFinding places a port finding on its host for the report-wide list.
+// Host-level findings have no port.
+type ReportFinding struct {
+	Finding
+	Addr     string `json:"addr"`
+	Hostname string `json:"hostname,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// Target is "addr:port/proto", or just the address for host-level findings
+func (f ReportFinding) Target() string {
+	if f.Port == 0 {
+		return f.Addr
+	}
+	return fmt.Sprintf("%s:%d/%s", f.Addr, f.Port, f.Protocol)
+}
+
+// hostFindings flattens the host-level findings and those of every port
+func hostFindings(h Host) []ReportFinding {
+	var out []ReportFinding
+	for _, f := range h.Detected {
+		out = append(out, ReportFinding{Finding: f, Addr: h.PrimaryAddr(), Hostname: h.PrimaryName()})
+	}
+	for _, p := range h.Ports.Ports {
+		for _, f := range p.Risk.Findings {
+			out = append(out, ReportFinding{
+				Finding:  f,
+				Addr:     h.PrimaryAddr(),
+				Hostname: h.PrimaryName(),
+				Port:     p.PortId,
+				Protocol: p.Protocol,
+			})
+		}
+	}
+	return out
+}
+
+// sortFindings orders findings worst first, then by host and port
+func sortFindings(findings []ReportFinding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if riskRank(a.Level) != riskRank(b.Level) {
+			return riskRank(a.Level) > riskRank(b.Level)
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if c := compareAddrs(a.Addr, b.Addr); c != 0 {
+			return c < 0
+		}
+		return a.Port < b.Port
+	})
+}
+
+var anchorEscaper = strings.NewReplacer(".", "-", ":", "-")
+
+// hostAnchor is the id of a host card, used to link findings to hosts.
+// Hosts without an address are identified by their hostname instead.
+func hostAnchor(addr, hostname string) string {
+	if addr == "" {
+		return "host-name-" + anchorEscaper.Replace(strings.ToLower(hostname))
+	}
+	return "host-" + anchorEscaper.Replace(addr)
+}
+
+// Anchor links the finding to its host card
+func (f ReportFinding) Anchor() string {
+	return hostAnchor(f.Addr, f.Hostname)
+}
+
+// IssueGroup is one issue across every host it was found on
+type IssueGroup struct {
+	Finding
+	Hosts    int             `json:"hosts"`
+	Affected []ReportFinding `json:"affected"`
+}
+
+// groupFindings turns the host-centric findings into an issue-centric view:
+// one group per rule, worst first, then by how many hosts are affected
+func groupFindings(findings []ReportFinding) []IssueGroup {
+	index := make(map[string]int)
+	var groups []IssueGroup
+	hosts := make(map[string]map[string]bool)
+	for _, f := range findings {
+		i, ok := index[f.RuleID]
+		if !ok {
+			i = len(groups)
+			index[f.RuleID] = i
+			groups = append(groups, IssueGroup{Finding: f.Finding})
+			hosts[f.RuleID] = make(map[string]bool)
+		}
+		g := &groups[i]
+		g.Affected = append(g.Affected, f)
+		if f.Score > g.Score {
+			g.Score = f.Score
+		}
+		if !hosts[f.RuleID][f.Addr] {
+			hosts[f.RuleID][f.Addr] = true
+			g.Hosts++
+		}
+	}
+	for i := range groups {
+		sortFindings(groups[i].Affected)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if riskRank(a.Level) != riskRank(b.Level) {
+			return riskRank(a.Level) > riskRank(b.Level)
+		}
+		if a.Hosts != b.Hosts {
+			return a.Hosts > b.Hosts
+		}
+		return a.Title < b.Title
+	})
+	return groups
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestScorePort(t *testing.T) {
+	withState := func(p Port, state string) Port {
+		p.State.State = state
+		return p
+	}
+	mysql := testPort(3306, "mysql")
+	eol := testPort(80, "http")
+	eol.Service.Product = "Apache httpd"
+	eol.Detected = []Finding{{RuleID: "eol", Level: RiskHigh, Score: 30}}
+
+	tests := []struct {
+		name      string
+		port      Port
+		wantRules []string
+		wantScore int
+		wantLevel string
+	}{
+		{"telnet", testPort(23, "telnet"), []string{"cleartext-remote-shell"}, 50, RiskCritical},
+		{"ftp", testPort(21, "ftp"), []string{"cleartext-ftp"}, 30, RiskHigh},
+		{"open|filtered counts half", withState(testPort(21, "ftp"), "open|filtered"), []string{"cleartext-ftp"}, 15, RiskHigh},
+		{"closed is not scored", withState(testPort(23, "telnet"), "closed"), nil, 0, RiskInfo},
+		{"filtered is not scored", withState(testPort(23, "telnet"), "filtered"), nil, 0, RiskInfo},
+		{"level follows the score", mysql, []string{"database-exposed"}, 20, RiskMedium},
+		{"detected findings are scored", eol, []string{"eol"}, 30, RiskHigh},
+		{"unidentified web server", testPort(443, "https"), []string{"web-unidentified"}, 15, RiskMedium},
+	}
+	for _, tt := range tests {
+		got := scorePort(tt.port, defaultRiskRules)
+		var rules []string
+		for _, f := range got.Findings {
+			rules = append(rules, f.RuleID)
+		}
+		if !reflect.DeepEqual(rules, tt.wantRules) {
+			t.Errorf("%s: rules %v, want %v", tt.name, rules, tt.wantRules)
+		}
+		if got.Score != tt.wantScore || got.Level != tt.wantLevel {
+			t.Errorf("%s: score %d (%s), want %d (%s)", tt.name, got.Score, got.Level, tt.wantScore, tt.wantLevel)
+		}
+	}
+}
+
+func TestScorePortCapsAt100(t *testing.T) {
+	rules := []RiskRule{
+		{Finding: Finding{RuleID: "a", Level: RiskLow, Score: 60}, Match: func(Port) bool { return true }},
+		{Finding: Finding{RuleID: "b", Level: RiskLow, Score: 60}, Match: func(Port) bool { return true }},
+	}
+	got := scorePort(testPort(80, "http"), rules)
+	// the level follows the capped score, never below the worst finding
+	if got.Score != 100 || got.Level != RiskCritical {
+		t.Errorf("score %d (%s), want 100 (critical)", got.Score, got.Level)
+	}
+}
+
+func TestScoreHost(t *testing.T) {
+	h := testHost("10.0.0.1", "up", testPort(25, "smtp"), testPort(3306, "mysql"), testPort(22, "ssh"))
+	scoreHost(&h, defaultRiskRules)
+
+	// smtp-exposed 10 + database-exposed 20 + ssh-unidentified 20
+	if h.Risk.Score != 50 {
+		t.Errorf("score = %d, want 50", h.Risk.Score)
+	}
+	if h.Risk.Findings != 3 {
+		t.Errorf("findings = %d, want 3", h.Risk.Findings)
+	}
+	if h.Risk.Level != RiskMedium {
+		t.Errorf("level = %s, want medium (the worst port)", h.Risk.Level)
+	}
+	if h.Ports.Ports[1].Risk.Level != RiskMedium {
+		t.Errorf("port risk not stored: %+v", h.Ports.Ports[1].Risk)
+	}
+
+	capped := testHost("10.0.0.2", "up", testPort(23, "telnet"), testPort(21, "ftp"), testPort(513, "rlogin"))
+	scoreHost(&capped, defaultRiskRules)
+	if capped.Risk.Score != 100 || capped.Risk.Level != RiskCritical {
+		t.Errorf("capped host: score %d (%s), want 100 (critical)", capped.Risk.Score, capped.Risk.Level)
+	}
+
+	eolOS := testHost("10.0.0.4", "up", testPort(25, "smtp"))
+	eolOS.Detected = []Finding{{RuleID: "eol-os", Level: RiskHigh, Score: 30}}
+	scoreHost(&eolOS, defaultRiskRules)
+	// host-level findings count towards the score, level and finding count
+	if eolOS.Risk.Score != 40 || eolOS.Risk.Level != RiskHigh || eolOS.Risk.Findings != 2 {
+		t.Errorf("host-level finding: score %d (%s), %d findings; want 40 (high), 2", eolOS.Risk.Score, eolOS.Risk.Level, eolOS.Risk.Findings)
+	}
+
+	quiet := testHost("10.0.0.3", "up")
+	scoreHost(&quiet, defaultRiskRules)
+	if quiet.Risk.Score != 0 || quiet.Risk.Level != RiskInfo {
+		t.Errorf("host without ports: score %d (%s)", quiet.Risk.Score, quiet.Risk.Level)
+	}
+}
+
+func TestSortFindings(t *testing.T) {
+	findings := []ReportFinding{
+		{Finding: Finding{Level: RiskMedium, Score: 15}, Addr: "10.0.0.10", Port: 80},
+		{Finding: Finding{Level: RiskMedium, Score: 15}, Addr: "10.0.0.9", Port: 443},
+		{Finding: Finding{Level: RiskMedium, Score: 15}, Addr: "10.0.0.9", Port: 80},
+		{Finding: Finding{Level: RiskMedium, Score: 20}, Addr: "10.0.0.100", Port: 22},
+		{Finding: Finding{Level: RiskCritical, Score: 50}, Addr: "10.0.0.200", Port: 23},
+	}
+	sortFindings(findings)
+	var got []string
+	for _, f := range findings {
+		got = append(got, fmt.Sprintf("%s:%d", f.Addr, f.Port))
+	}
+	want := []string{
+		"10.0.0.200:23", // worst level first
+		"10.0.0.100:22", // then the higher score
+		"10.0.0.9:80",
+		"10.0.0.9:443",
+		"10.0.0.10:80",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestHostAnchor(t *testing.T) {
+	named := Host{Hostnames: Hostnames{Names: []Hostname{{Name: "Web01.example.com"}}}}
+	tests := []struct {
+		host Host
+		want string
+	}{
+		{testHost("10.0.0.1", "up"), "host-10-0-0-1"},
+		{testHost("2001:db8::1", "up"), "host-2001-db8--1"},
+		{named, "host-name-web01-example-com"},
+	}
+	for _, tt := range tests {
+		if got := tt.host.Anchor(); got != tt.want {
+			t.Errorf("Anchor() = %q, want %q", got, tt.want)
+		}
+	}
+	// findings link to the same id as the card
+	f := ReportFinding{Hostname: "Web01.example.com"}
+	if f.Anchor() != named.Anchor() {
+		t.Errorf("finding anchor %q does not match the card %q", f.Anchor(), named.Anchor())
+	}
+}
+
+func TestGroupFindings(t *testing.T) {
+	finding := func(rule, level string, score int, addr string, port int) ReportFinding {
+		return ReportFinding{Finding: Finding{RuleID: rule, Title: rule, Level: level, Score: score}, Addr: addr, Port: port}
+	}
+	groups := groupFindings([]ReportFinding{
+		finding("smtp-exposed", RiskLow, 10, "10.0.0.1", 25),
+		finding("database-exposed", RiskMedium, 20, "10.0.0.10", 3306),
+		finding("web-unidentified", RiskMedium, 15, "10.0.0.1", 80),
+		finding("database-exposed", RiskMedium, 20, "10.0.0.9", 5432),
+		finding("web-unidentified", RiskMedium, 15, "10.0.0.1", 8080),
+		finding("cleartext-remote-shell", RiskCritical, 50, "10.0.0.2", 23),
+		finding("database-exposed", RiskMedium, 10, "10.0.0.9", 3306),
+	})
+
+	type summary struct {
+		Rule     string
+		Hosts    int
+		Affected int
+		Score    int
+	}
+	var got []summary
+	for _, g := range groups {
+		got = append(got, summary{g.RuleID, g.Hosts, len(g.Affected), g.Score})
+	}
+	want := []summary{
+		{"cleartext-remote-shell", 1, 1, 50}, // worst level first
+		{"database-exposed", 2, 3, 20},       // then by affected hosts, not by findings
+		{"web-unidentified", 1, 2, 15},
+		{"smtp-exposed", 1, 1, 10},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	var targets []string
+	for _, f := range groups[1].Affected {
+		targets = append(targets, fmt.Sprintf("%s:%d", f.Addr, f.Port))
+	}
+	if want := []string{"10.0.0.9:5432", "10.0.0.10:3306", "10.0.0.9:3306"}; !reflect.DeepEqual(targets, want) {
+		t.Errorf("affected = %v, want %v", targets, want)
+	}
+}
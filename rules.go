@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RulesFile is the on-disk format for -rules. YAML is the primary syntax;
+// since YAML is a superset of JSON, a JSON file with the same keys also works.
+//
+//	replace_defaults: false
+//	rules:
+//	  - id: telnet-enabled
+//	    title: Telnet enabled
+//	    severity: critical
+//	    description: Telnet sends credentials in clear text.
+//	    remediation: Disable telnet and use SSH.
+//	    match:
+//	      ports: [23]
+//	      protocol: tcp
+//	      service: "^telnet$"
+type RulesFile struct {
+	ReplaceDefaults bool       `yaml:"replace_defaults"`
+	Rules           []RuleSpec `yaml:"rules"`
+}
+
+// RuleSpec is one user-defined rule. Every condition set under Match must hold.
+type RuleSpec struct {
+	ID          string    `yaml:"id"`
+	Title       string    `yaml:"title"`
+	Severity    string    `yaml:"severity"`
+	Score       *int      `yaml:"score"`
+	Description string    `yaml:"description"`
+	Remediation string    `yaml:"remediation"`
+	Match       MatchSpec `yaml:"match"`
+}
+
+type MatchSpec struct {
+	Ports        []int  `yaml:"ports"`
+	Protocol     string `yaml:"protocol"`
+	Service      string `yaml:"service"`       // regex on Service.Name
+	Product      string `yaml:"product"`       // regex on Service.Product
+	Version      string `yaml:"version"`       // constraints, e.g. ">=2.4.0, <2.4.50"
+	Script       string `yaml:"script"`        // script ID, glob allowed (smb-vuln-*); checks host scripts too when no port condition is set
+	ScriptOutput string `yaml:"script_output"` // regex on the script output
+}
+
+// defaultSeverityScores is used when a rule does not set its own score
+var defaultSeverityScores = map[string]int{
+	RiskCritical: 50,
+	RiskHigh:     30,
+	RiskMedium:   15,
+	RiskLow:      5,
+	RiskInfo:     0,
+}
+
+// compile turns the spec into a RiskRule, validating regexes and constraints
+func (r RuleSpec) compile() (RiskRule, error) {
+	if r.ID == "" {
+		return RiskRule{}, fmt.Errorf("rule without id")
+	}
+	severity := strings.ToLower(r.Severity)
+	score, ok := defaultSeverityScores[severity]
+	if !ok {
+		return RiskRule{}, fmt.Errorf("rule %s: unknown severity %q", r.ID, r.Severity)
+	}
+	if r.Score != nil {
+		score = *r.Score
+	}
+	m := r.Match
+	if len(m.Ports) == 0 && m.Protocol == "" && m.Service == "" && m.Product == "" && m.Version == "" && m.Script == "" && m.ScriptOutput == "" {
+		// an empty match would flag every open port
+		return RiskRule{}, fmt.Errorf("rule %s: match sets no condition", r.ID)
+	}
+	title := r.Title
+	if title == "" {
+		title = r.ID
+	}
+
+	compileRe := func(field, expr string) (*regexp.Regexp, error) {
+		if expr == "" {
+			return nil, nil
+		}
+		re, err := regexp.Compile("(?i)" + expr)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %s: %w", r.ID, field, err)
+		}
+		return re, nil
+	}
+	serviceRe, err := compileRe("service", m.Service)
+	if err != nil {
+		return RiskRule{}, err
+	}
+	productRe, err := compileRe("product", m.Product)
+	if err != nil {
+		return RiskRule{}, err
+	}
+	outputRe, err := compileRe("script_output", m.ScriptOutput)
+	if err != nil {
+		return RiskRule{}, err
+	}
+	versions, err := parseVersionConstraints(m.Version)
+	if err != nil {
+		return RiskRule{}, fmt.Errorf("rule %s: version: %w", r.ID, err)
+	}
+	if m.Script != "" {
+		if _, err := path.Match(m.Script, ""); err != nil {
+			return RiskRule{}, fmt.Errorf("rule %s: script: %w", r.ID, err)
+		}
+	}
+
+	// scriptsMatch reports whether any script satisfies the script conditions
+	scriptsMatch := func(scripts []Script) bool {
+		for _, s := range scripts {
+			if m.Script != "" {
+				if ok, _ := path.Match(m.Script, s.ID); !ok {
+					continue
+				}
+			}
+			if outputRe != nil && !outputRe.MatchString(s.Output) {
+				continue
+			}
+			return true
+		}
+		return false
+	}
+	hasScriptCond := m.Script != "" || outputRe != nil
+
+	match := func(p Port) bool {
+		if len(m.Ports) > 0 {
+			found := false
+			for _, n := range m.Ports {
+				if n == p.PortId {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		if m.Protocol != "" && !strings.EqualFold(m.Protocol, p.Protocol) {
+			return false
+		}
+		if serviceRe != nil && !serviceRe.MatchString(p.Service.Name) {
+			return false
+		}
+		if productRe != nil && !productRe.MatchString(p.Service.Product) {
+			return false
+		}
+		if !versionSatisfies(p.Service.Version, versions) {
+			return false
+		}
+		if hasScriptCond && !scriptsMatch(p.Scripts) {
+			return false
+		}
+		return true
+	}
+
+	// rules with only script conditions also check the host scripts, which
+	// is where nmap puts smb-vuln-* and other host-level results
+	var matchHost func(h Host) bool
+	portCond := len(m.Ports) > 0 || m.Protocol != "" || serviceRe != nil || productRe != nil || len(versions) > 0
+	if hasScriptCond && !portCond {
+		matchHost = func(h Host) bool { return scriptsMatch(h.HostScripts) }
+	}
+
+	return RiskRule{
+		Finding: Finding{
+			RuleID:      r.ID,
+			Title:       title,
+			Level:       severity,
+			Score:       score,
+			Description: r.Description,
+			Remediation: r.Remediation,
+		},
+		Match:     match,
+		MatchHost: matchHost,
+	}, nil
+}
+
+// loadRulesFile reads a rules file and merges it with the built-in rules.
+// A user rule with the same id as a built-in one replaces it.
+func loadRulesFile(filename string, defaults []RiskRule) ([]RiskRule, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var rf RulesFile
+	if err := yaml.Unmarshal(b, &rf); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", filename, err)
+	}
+
+	custom := make(map[string]bool)
+	var user []RiskRule
+	for _, spec := range rf.Rules {
+		rule, err := spec.compile()
+		if err != nil {
+			return nil, err
+		}
+		if custom[rule.RuleID] {
+			return nil, fmt.Errorf("duplicate rule id %q", rule.RuleID)
+		}
+		custom[rule.RuleID] = true
+		user = append(user, rule)
+	}
+
+	if rf.ReplaceDefaults {
+		return user, nil
+	}
+	var merged []RiskRule
+	for _, d := range defaults {
+		if !custom[d.RuleID] {
+			merged = append(merged, d)
+		}
+	}
+	return append(merged, user...), nil
+}
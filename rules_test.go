@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRuleSpecCompileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		spec RuleSpec
+	}{
+		{"no id", RuleSpec{Severity: "high", Match: MatchSpec{Ports: []int{23}}}},
+		{"bad severity", RuleSpec{ID: "x", Severity: "urgent", Match: MatchSpec{Ports: []int{23}}}},
+		{"no match conditions", RuleSpec{ID: "x", Severity: "high"}},
+		{"bad service regex", RuleSpec{ID: "x", Severity: "high", Match: MatchSpec{Service: "("}}},
+		{"bad output regex", RuleSpec{ID: "x", Severity: "high", Match: MatchSpec{ScriptOutput: "["}}},
+		{"bad version", RuleSpec{ID: "x", Severity: "high", Match: MatchSpec{Version: ">="}}},
+	}
+	for _, tt := range tests {
+		if _, err := tt.spec.compile(); err == nil {
+			t.Errorf("%s: compile succeeded, want error", tt.name)
+		}
+	}
+}
+
+func TestRuleSpecMatch(t *testing.T) {
+	apache := testPort(80, "http")
+	apache.Service.Product = "Apache httpd"
+	apache.Service.Version = "2.4.49"
+	tlsPort := testPort(443, "https")
+	tlsPort.Scripts = []Script{{ID: "ssl-enum-ciphers", Output: "TLSv1.0: ciphers"}}
+	smbHost := testHost("10.0.0.5", "up", testPort(445, "microsoft-ds"))
+	smbHost.HostScripts = []Script{{ID: "smb-vuln-ms17-010", Output: "State: VULNERABLE"}}
+
+	tests := []struct {
+		name     string
+		match    MatchSpec
+		port     Port
+		wantPort bool
+		host     Host
+		wantHost bool
+	}{
+		{name: "port and protocol", match: MatchSpec{Ports: []int{80}, Protocol: "tcp"}, port: apache, wantPort: true},
+		{name: "wrong protocol", match: MatchSpec{Ports: []int{80}, Protocol: "udp"}, port: apache},
+		{name: "product and version", match: MatchSpec{Product: "(?i)apache", Version: ">=2.4.0, <2.4.50"}, port: apache, wantPort: true},
+		{name: "version out of range", match: MatchSpec{Product: "(?i)apache", Version: "<2.4.49"}, port: apache},
+		{name: "port script", match: MatchSpec{Script: "ssl-enum-*", ScriptOutput: "TLSv1\\.0"}, port: tlsPort, wantPort: true},
+		{name: "host script", match: MatchSpec{Script: "smb-vuln-*", ScriptOutput: "VULNERABLE"}, port: apache, host: smbHost, wantHost: true},
+		{name: "host script output differs", match: MatchSpec{Script: "smb-vuln-*", ScriptOutput: "NOT VULNERABLE"}, port: apache, host: smbHost},
+		{name: "port condition skips host scripts", match: MatchSpec{Ports: []int{445}, Script: "smb-vuln-*"}, port: smbHost.Ports.Ports[0], host: smbHost},
+	}
+	for _, tt := range tests {
+		rule, err := RuleSpec{ID: "t", Severity: "high", Match: tt.match}.compile()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := rule.Match(tt.port); got != tt.wantPort {
+			t.Errorf("%s: Match = %v, want %v", tt.name, got, tt.wantPort)
+		}
+		got := len(hostRuleFindings(tt.host, []RiskRule{rule})) > 0
+		if got != tt.wantHost {
+			t.Errorf("%s: host match = %v, want %v", tt.name, got, tt.wantHost)
+		}
+	}
+}
+
+func TestLoadRulesFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "rules.yaml")
+	yaml := `rules:
+  - id: cleartext-remote-shell
+    title: Telnet
+    severity: low
+    match:
+      ports: [23]
+  - id: smb-vuln
+    title: SMB vulnerability
+    severity: critical
+    match:
+      script: smb-vuln-*
+`
+	if err := os.WriteFile(file, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := loadRulesFile(file, defaultRiskRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != len(defaultRiskRules)+1 {
+		t.Errorf("got %d rules, want %d", len(rules), len(defaultRiskRules)+1)
+	}
+	for _, r := range rules {
+		if r.RuleID == "cleartext-remote-shell" && r.Level != RiskLow {
+			t.Errorf("built-in rule not replaced: severity %q", r.Level)
+		}
+	}
+
+	dup := filepath.Join(t.TempDir(), "dup.yaml")
+	os.WriteFile(dup, []byte("rules:\n  - {id: a, severity: low, match: {ports: [21]}}\n  - {id: a, severity: low, match: {ports: [23]}}\n"), 0o600)
+	if _, err := loadRulesFile(dup, nil); err == nil {
+		t.Error("duplicate rule ids accepted")
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty.yaml")
+	os.WriteFile(empty, []byte("rules:\n  - id: typo\n    severity: high\n    mach:\n      ports: [23]\n"), 0o600)
+	if _, err := loadRulesFile(empty, nil); err == nil {
+		t.Error("rule without match conditions accepted")
+	}
+}
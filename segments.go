@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SegmentsFile is the on-disk format for -segments, YAML or JSON. Hosts
+// are placed in the most specific segment containing one of their addresses.
+//
+//	segments:
+//	  - name: DMZ
+//	    cidr: 10.0.1.0/24
+//	  - name: Servers
+//	    cidr: 10.0.0.0/16
+type SegmentsFile struct {
+	Segments []struct {
+		Name string `yaml:"name"`
+		CIDR string `yaml:"cidr"`
+	} `yaml:"segments"`
+}
+
+// Segment is a named network
+type Segment struct {
+	Name   string
+	Prefix netip.Prefix
+}
+
+// HostGrouper places hosts in named segments, falling back to automatic
+// per-subnet groups
+type HostGrouper struct {
+	Prefix4 int // e.g. 24
+	Prefix6 int // e.g. 64
+	Named   []Segment
+}
+
+// parseGroupSpec reads -group: "auto" for /24 and /64, or "N" / "N,M" for
+// the IPv4 and IPv6 prefix lengths
+func parseGroupSpec(spec string) (*HostGrouper, error) {
+	g := &HostGrouper{Prefix4: 24, Prefix6: 64}
+	spec = strings.TrimSpace(spec)
+	if spec == "auto" {
+		return g, nil
+	}
+	v4, v6, hasV6 := strings.Cut(spec, ",")
+	n, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(v4), "/"))
+	if err != nil || n < 0 || n > 32 {
+		return nil, fmt.Errorf("invalid IPv4 prefix %q", v4)
+	}
+	g.Prefix4 = n
+	if hasV6 {
+		n, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(v6), "/"))
+		if err != nil || n < 0 || n > 128 {
+			return nil, fmt.Errorf("invalid IPv6 prefix %q", v6)
+		}
+		g.Prefix6 = n
+	}
+	return g, nil
+}
+
+// loadSegmentsFile reads the named CIDRs for -segments
+func loadSegmentsFile(filename string) ([]Segment, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var sf SegmentsFile
+	if err := yaml.Unmarshal(b, &sf); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", filename, err)
+	}
+	var out []Segment
+	for _, s := range sf.Segments {
+		p, err := netip.ParsePrefix(strings.TrimSpace(s.CIDR))
+		if err != nil {
+			return nil, fmt.Errorf("segment %q: %w", s.Name, err)
+		}
+		name := s.Name
+		if name == "" {
+			name = p.Masked().String()
+		}
+		out = append(out, Segment{Name: name, Prefix: p.Masked()})
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%s: no segments defined", filename)
+	}
+	return out, nil
+}
+
+// hostIP is the host's first IP address; MAC-only hosts have none
+func hostIP(h Host) (netip.Addr, bool) {
+	for _, a := range h.Addresses {
+		if a.AddrType == "mac" {
+			continue
+		}
+		if ip, err := netip.ParseAddr(a.Addr); err == nil {
+			return ip.Unmap(), true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// compareHosts orders hosts numerically by IP, IPv4 before IPv6, hosts
+// without an IP last
+func compareHosts(a, b Host) int {
+	ipA, okA := hostIP(a)
+	ipB, okB := hostIP(b)
+	switch {
+	case !okA && !okB:
+		return strings.Compare(a.PrimaryAddr(), b.PrimaryAddr())
+	case !okA:
+		return 1
+	case !okB:
+		return -1
+	}
+	return ipA.Compare(ipB)
+}
+
+// HostGroup is one collapsible network section of the report
+type HostGroup struct {
+	Name  string // segment name, or the subnet for automatic groups
+	CIDR  string // "" for hosts without an IP address
+	Hosts []Host
+
+	order  int          // named segments first, in file order
+	prefix netip.Prefix // automatic groups sort by network address
+}
+
+// Up is the number of hosts that answered
+func (g HostGroup) Up() int {
+	n := 0
+	for _, h := range g.Hosts {
+		if h.Status.State == "up" {
+			n++
+		}
+	}
+	return n
+}
+
+// OpenPorts is the number of open ports across the group
+func (g HostGroup) OpenPorts() int {
+	n := 0
+	for _, h := range g.Hosts {
+		n += len(h.OpenPorts())
+	}
+	return n
+}
+
+// Findings is the number of risk findings across the group
+func (g HostGroup) Findings() int {
+	n := 0
+	for _, h := range g.Hosts {
+		n += h.Risk.Findings
+	}
+	return n
+}
+
+// Level is the worst host risk level in the group
+func (g HostGroup) Level() string {
+	level := RiskInfo
+	for _, h := range g.Hosts {
+		if riskRank(h.Risk.Level) > riskRank(level) {
+			level = h.Risk.Level
+		}
+	}
+	return level
+}
+
+// Group sorts the hosts numerically and splits them into sections
+func (g *HostGrouper) Group(hosts []Host) []HostGroup {
+	sorted := append([]Host(nil), hosts...)
+	sort.SliceStable(sorted, func(i, j int) bool { return compareHosts(sorted[i], sorted[j]) < 0 })
+
+	index := make(map[string]int)
+	var groups []HostGroup
+	for _, h := range sorted {
+		key, group := g.place(h)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, group)
+		}
+		groups[i].Hosts = append(groups[i].Hosts, h)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if a.order != b.order {
+			return a.order < b.order
+		}
+		if a.prefix.IsValid() != b.prefix.IsValid() {
+			return a.prefix.IsValid()
+		}
+		if c := a.prefix.Addr().Compare(b.prefix.Addr()); c != 0 {
+			return c < 0
+		}
+		return a.prefix.Bits() < b.prefix.Bits()
+	})
+	return groups
+}
+
+// place returns the group key and an empty group for the host
+func (g *HostGrouper) place(h Host) (string, HostGroup) {
+	ip, ok := hostIP(h)
+	if !ok {
+		return "", HostGroup{Name: "No IP address", order: len(g.Named)}
+	}
+
+	best := -1
+	for i, s := range g.Named {
+		if s.Prefix.Contains(ip) && (best < 0 || s.Prefix.Bits() > g.Named[best].Prefix.Bits()) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		s := g.Named[best]
+		return "segment:" + strconv.Itoa(best), HostGroup{Name: s.Name, CIDR: s.Prefix.String(), order: best}
+	}
+
+	bits := g.Prefix6
+	if ip.Is4() {
+		bits = g.Prefix4
+	}
+	p, err := ip.Prefix(bits)
+	if err != nil {
+		return "", HostGroup{Name: "No IP address", order: len(g.Named)}
+	}
+	return p.String(), HostGroup{Name: p.String(), CIDR: p.String(), order: len(g.Named), prefix: p}
+}
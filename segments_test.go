@@ -0,0 +1,136 @@
+package main
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseGroupSpec(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want4   int
+		want6   int
+		wantErr bool
+	}{
+		{spec: "auto", want4: 24, want6: 64},
+		{spec: "16", want4: 16, want6: 64},
+		{spec: "/20,/48", want4: 20, want6: 48},
+		{spec: " 28 , 56 ", want4: 28, want6: 56},
+		{spec: "", wantErr: true},
+		{spec: "33", wantErr: true},
+		{spec: "-1", wantErr: true},
+		{spec: "24,129", wantErr: true},
+		{spec: "24,x", wantErr: true},
+		{spec: "subnet", wantErr: true},
+	}
+	for _, tt := range tests {
+		g, err := parseGroupSpec(tt.spec)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseGroupSpec(%q) succeeded", tt.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseGroupSpec(%q): %v", tt.spec, err)
+			continue
+		}
+		if g.Prefix4 != tt.want4 || g.Prefix6 != tt.want6 {
+			t.Errorf("parseGroupSpec(%q) = /%d, /%d; want /%d, /%d", tt.spec, g.Prefix4, g.Prefix6, tt.want4, tt.want6)
+		}
+	}
+}
+
+// groupSummary flattens groups to "name: addr addr" for comparison
+func groupSummary(groups []HostGroup) []string {
+	var out []string
+	for _, g := range groups {
+		s := g.Name + ":"
+		for _, h := range g.Hosts {
+			s += " " + h.PrimaryAddr()
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestHostGrouperAuto(t *testing.T) {
+	macOnly := Host{Addresses: []Address{{Addr: "00:11:22:33:44:55", AddrType: "mac"}}, Status: Status{State: "up"}}
+	hosts := []Host{
+		testHost("2001:db8:0:1::5", "up"),
+		testHost("10.0.1.10", "up"),
+		macOnly,
+		testHost("10.0.0.10", "up"),
+		testHost("2001:db8::10", "up"),
+		testHost("10.0.0.9", "up"),
+		testHost("2001:db8::9", "up"),
+		testHost("9.9.9.9", "up"),
+	}
+	g, _ := parseGroupSpec("auto")
+	got := groupSummary(g.Group(hosts))
+	want := []string{
+		"9.9.9.0/24: 9.9.9.9",
+		"10.0.0.0/24: 10.0.0.9 10.0.0.10",
+		"10.0.1.0/24: 10.0.1.10",
+		"2001:db8::/64: 2001:db8::9 2001:db8::10",
+		"2001:db8:0:1::/64: 2001:db8:0:1::5",
+		"No IP address: 00:11:22:33:44:55",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("groups:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestHostGrouperNamedSegments(t *testing.T) {
+	g := &HostGrouper{Prefix4: 24, Prefix6: 64, Named: []Segment{
+		{Name: "Servers", Prefix: netip.MustParsePrefix("10.0.0.0/16")},
+		{Name: "DMZ", Prefix: netip.MustParsePrefix("10.0.1.0/24")},
+	}}
+	hosts := []Host{
+		testHost("10.0.1.5", "up"),
+		testHost("10.0.2.5", "up"),
+		testHost("192.168.1.1", "up"),
+		{Status: Status{State: "up"}},
+	}
+	got := groupSummary(g.Group(hosts))
+	want := []string{
+		"Servers: 10.0.2.5",
+		"DMZ: 10.0.1.5",
+		"192.168.1.0/24: 192.168.1.1",
+		"No IP address: ",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("groups:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestLoadSegmentsFile(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "segments.yaml")
+	os.WriteFile(good, []byte("segments:\n  - name: DMZ\n    cidr: 10.0.1.7/24\n  - cidr: 2001:db8::/32\n"), 0o600)
+	segs, err := loadSegmentsFile(good)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Segment{
+		{Name: "DMZ", Prefix: netip.MustParsePrefix("10.0.1.0/24")},
+		{Name: "2001:db8::/32", Prefix: netip.MustParsePrefix("2001:db8::/32")},
+	}
+	if !reflect.DeepEqual(segs, want) {
+		t.Errorf("got %v, want %v", segs, want)
+	}
+
+	for name, body := range map[string]string{
+		"empty.yaml": "segments: []\n",
+		"bad.yaml":   "segments:\n  - name: x\n    cidr: 10.0.0.0/33\n",
+	} {
+		file := filepath.Join(dir, name)
+		os.WriteFile(file, []byte(body), 0o600)
+		if _, err := loadSegmentsFile(file); err == nil {
+			t.Errorf("%s: loaded without error", name)
+		}
+	}
+}
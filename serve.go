@@ -0,0 +1,513 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ScanRecord is the index entry kept next to each uploaded scan
+type ScanRecord struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"` // uploaded file name
+	Uploaded  time.Time `json:"uploaded"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	Args      string    `json:"args"`
+	StartStr  string    `json:"startstr"`
+	Hosts     int       `json:"hosts"`
+	Up        int       `json:"hosts_up"`
+	OpenPorts int       `json:"open_ports"`
+}
+
+// scanIDPattern matches the IDs newScanID makes, so request paths can't
+// reach outside the scan directory
+var scanIDPattern = regexp.MustCompile(`^[0-9]{8}-[0-9]{6}-[0-9a-f]{8}$`)
+
+func newScanID(uploaded time.Time, sum string) string {
+	return uploaded.UTC().Format("20060102-150405") + "-" + sum[:8]
+}
+
+// scanStore keeps uploads as <id>.xml with an <id>.json record in one
+// directory. Scans describe the network, so the files are owner-only.
+type scanStore struct {
+	dir string
+	mu  sync.Mutex // serializes the duplicate check and the record write
+}
+
+func (s *scanStore) xmlPath(id string) string  { return filepath.Join(s.dir, id+".xml") }
+func (s *scanStore) jsonPath(id string) string { return filepath.Join(s.dir, id+".json") }
+
+// List returns every record, newest first
+func (s *scanStore) List() ([]ScanRecord, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var out []ScanRecord
+	for _, p := range paths {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		var rec ScanRecord
+		if err := json.Unmarshal(b, &rec); err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		out = append(out, rec)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Uploaded.Equal(out[j].Uploaded) {
+			return out[i].Uploaded.After(out[j].Uploaded)
+		}
+		return out[i].ID > out[j].ID
+	})
+	return out, nil
+}
+
+// Get loads one record
+func (s *scanStore) Get(id string) (ScanRecord, error) {
+	var rec ScanRecord
+	if !scanIDPattern.MatchString(id) {
+		return rec, os.ErrNotExist
+	}
+	b, err := os.ReadFile(s.jsonPath(id))
+	if err != nil {
+		return rec, err
+	}
+	return rec, json.Unmarshal(b, &rec)
+}
+
+// Save stores an upload after checking it is nmap XML. Uploading the same
+// file twice returns the first record, with created false.
+func (s *scanStore) Save(name string, r io.Reader) (ScanRecord, bool, error) {
+	tmp, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return ScanRecord{}, false, err
+	}
+	defer os.Remove(tmp.Name()) // renamed away on success
+	defer tmp.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if err != nil {
+		return ScanRecord{}, false, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return ScanRecord{}, false, err
+	}
+	rec, err := inspectScan(tmp)
+	if err != nil {
+		return ScanRecord{}, false, err
+	}
+	rec.Name = filepath.Base(name)
+	rec.Size = size
+	rec.SHA256 = hex.EncodeToString(h.Sum(nil))
+	rec.Uploaded = time.Now().UTC().Truncate(time.Second)
+	rec.ID = newScanID(rec.Uploaded, rec.SHA256)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, err := s.List()
+	if err != nil {
+		return ScanRecord{}, false, err
+	}
+	for _, e := range existing {
+		if e.SHA256 == rec.SHA256 {
+			return e, false, nil
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return ScanRecord{}, false, err
+	}
+	if err := os.Rename(tmp.Name(), s.xmlPath(rec.ID)); err != nil {
+		return ScanRecord{}, false, err
+	}
+	b, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return ScanRecord{}, false, err
+	}
+	return rec, true, os.WriteFile(s.jsonPath(rec.ID), b, 0o600)
+}
+
+// Upload validation errors, answered with 400 Bad Request
+var (
+	errNotNmap    = errors.New("not an nmap XML file (no <nmaprun> root element)")
+	errInvalidXML = errors.New("invalid XML")
+)
+
+// inspectScan reads the whole document once, so malformed uploads are
+// rejected before anything is rendered, and counts hosts and open ports
+// for the index
+func inspectScan(r io.Reader) (ScanRecord, error) {
+	var rec ScanRecord
+	decoder := xml.NewDecoder(r)
+	root, inPort := false, false
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if !root {
+				return rec, errNotNmap
+			}
+			return rec, fmt.Errorf("%w: %v", errInvalidXML, err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if !root {
+				if t.Name.Local != "nmaprun" {
+					return rec, errNotNmap
+				}
+				info := runInfoFromAttrs(t)
+				rec.Args, rec.StartStr = info.Args, info.StartStr
+				root = true
+				continue
+			}
+			switch t.Name.Local {
+			case "host":
+				rec.Hosts++
+			case "status":
+				if attrValue(t, "state") == "up" {
+					rec.Up++
+				}
+			case "port":
+				inPort = true
+			case "state":
+				if inPort && attrValue(t, "state") == "open" {
+					rec.OpenPorts++
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local == "port" {
+				inPort = false
+			}
+		}
+	}
+	if !root {
+		return rec, errNotNmap
+	}
+	return rec, nil
+}
+
+func attrValue(se xml.StartElement, name string) string {
+	for _, a := range se.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// scanServer serves the index, uploads and stored reports
+type scanServer struct {
+	store     *scanStore
+	tpl       *template.Template
+	maxUpload int64
+}
+
+func (s *scanServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleIndex)
+	mux.HandleFunc("/upload", s.handleUpload)
+	mux.HandleFunc("/scans/", s.handleScan)
+	return mux
+}
+
+// handleIndex lists the stored scans with an upload form
+func (s *scanServer) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	scans, err := s.store.List()
+	if err != nil {
+		log.Printf("serve: list scans: %v", err)
+		http.Error(w, "cannot read the scan index", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	data := struct {
+		CSS     template.CSS
+		Scans   []ScanRecord
+		Host    string
+		Version string
+	}{template.CSS(defaultCSS), scans, r.Host, appVersion}
+	if err := indexPage.Execute(w, data); err != nil {
+		log.Printf("serve: index: %v", err)
+	}
+}
+
+// handleUpload stores a multipart ("file" field) or raw XML body and
+// renders the report straight into the response
+func (s *scanServer) handleUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxUpload)
+
+	body, name, err := uploadBody(r)
+	if err != nil {
+		s.uploadFailed(w, err)
+		return
+	}
+	rec, created, err := s.store.Save(name, body)
+	if err != nil {
+		s.uploadFailed(w, err)
+		return
+	}
+	if !created {
+		// the same file was uploaded before: point at that report
+		log.Printf("serve: duplicate of %s (%s) from %s", rec.ID, rec.Name, r.RemoteAddr)
+		http.Redirect(w, r, "/scans/"+rec.ID, http.StatusSeeOther)
+		return
+	}
+	log.Printf("serve: stored %s (%s, %d hosts) from %s", rec.ID, rec.Name, rec.Hosts, r.RemoteAddr)
+
+	w.Header().Set("Location", "/scans/"+rec.ID)
+	s.render(w, rec, http.StatusCreated)
+}
+
+// uploadFailed answers a failed upload: 413 over the size limit, 400 for
+// anything that is not nmap XML, 500 otherwise
+func (s *scanServer) uploadFailed(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		http.Error(w, fmt.Sprintf("upload larger than %d MB", s.maxUpload>>20), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, errNotNmap), errors.Is(err, errInvalidXML), errors.Is(err, errNoFileField):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("serve: upload: %v", err)
+		http.Error(w, "cannot store the upload", http.StatusInternalServerError)
+	}
+}
+
+// errNoFileField is a multipart upload without the "file" field
+var errNoFileField = errors.New(`multipart upload has no "file" field`)
+
+// uploadBody picks the "file" part of a multipart form, or the raw body.
+// Raw uploads may name the file with ?name=.
+func uploadBody(r *http.Request) (io.Reader, string, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			name = "upload.xml"
+		}
+		return r.Body, name, nil
+	}
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, "", err
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, "", errNoFileField
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if part.FormName() == "file" {
+			name := part.FileName()
+			if name == "" {
+				name = "upload.xml"
+			}
+			return part, name, nil
+		}
+	}
+}
+
+// handleScan renders /scans/<id> and serves the original at /scans/<id>.xml
+func (s *scanServer) handleScan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/scans/")
+	id, raw := strings.CutSuffix(id, ".xml")
+	rec, err := s.store.Get(id)
+	if errors.Is(err, os.ErrNotExist) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("serve: read %s: %v", id, err)
+		http.Error(w, "cannot read the scan record", http.StatusInternalServerError)
+		return
+	}
+	if raw {
+		w.Header().Set("Content-Type", "application/xml")
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": rec.Name}))
+		http.ServeFile(w, r, s.store.xmlPath(rec.ID))
+		return
+	}
+	s.render(w, rec, http.StatusOK)
+}
+
+// render streams the HTML report for a stored scan. Once the header is
+// written an error can only be logged.
+func (s *scanServer) render(w http.ResponseWriter, rec ScanRecord, status int) {
+	path := s.store.xmlPath(rec.ID)
+	f, err := os.Open(path)
+	if err != nil {
+		log.Printf("serve: open %s: %v", rec.ID, err)
+		http.Error(w, "cannot read the scan", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	bw := bufio.NewWriter(w)
+	if _, err := renderHTML(bw, f, htmlOptions{Template: s.tpl, CSS: defaultCSS}); err != nil {
+		log.Printf("serve: render %s: %v", rec.ID, err)
+	}
+	bw.Flush()
+}
+
+// runServe is the serve subcommand:
+//
+//	nmap-html-converter serve [-addr 127.0.0.1:8080] [-dir nmap-scans]
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	var addr, dir, rulesPath, assetsPath string
+	var maxUploadMB int64
+	fs.StringVar(&addr, "addr", "127.0.0.1:8080", "listen address; use :8080 to accept connections from other machines")
+	fs.StringVar(&dir, "dir", "nmap-scans", "directory for uploaded scans and the index")
+	fs.Int64Var(&maxUploadMB, "max-upload", 100, "largest accepted upload in MB")
+	fs.StringVar(&rulesPath, "rules", "", "risk rules file (YAML or JSON) adding to or replacing the built-in rules (optional)")
+	fs.StringVar(&assetsPath, "assets", "", "asset inventory (CSV or JSON) joined to hosts (optional)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s serve [options]\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if rulesPath != "" {
+		rules, err := loadRulesFile(rulesPath, defaultRiskRules)
+		if err != nil {
+			return fmt.Errorf("load rules: %w", err)
+		}
+		riskRules = rules
+	}
+	if assetsPath != "" {
+		inv, err := loadAssetFile(assetsPath)
+		if err != nil {
+			return fmt.Errorf("load assets: %w", err)
+		}
+		assetInventory = inv
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	tpl, err := loadTemplate("")
+	if err != nil {
+		return err
+	}
+
+	s := &scanServer{store: &scanStore{dir: dir}, tpl: tpl, maxUpload: maxUploadMB << 20}
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("serve: listening on http://%s, storing scans in %s", addr, dir)
+	return srv.ListenAndServe()
+}
+
+// indexPage lists the stored scans, newest first
+var indexPage = template.Must(template.New("index").Parse(`<!doctype html>
+<html lang="en">
+<head>
+  <meta charset="utf-8"/>
+  <meta name="viewport" content="width=device-width,initial-scale=1"/>
+  <title>Nmap Scans</title>
+  <style>{{.CSS}}</style>
+</head>
+<body>
+  <header class="topbar">
+    <div class="container">
+      <div class="brand">
+        <svg class="logo" viewBox="0 0 24 24" aria-hidden="true">
+          <path d="M12 2L2 7l10 5 10-5-10-5zM2 17l10 5 10-5M2 12l10 5 10-5" stroke="currentColor" stroke-width="1.5" stroke-linecap="round" stroke-linejoin="round" fill="none"/>
+        </svg>
+        <div>
+          <h1>Nmap Scans</h1>
+          <p class="muted">{{len .Scans}} uploaded scan{{if ne (len .Scans) 1}}s{{end}}</p>
+        </div>
+      </div>
+    </div>
+  </header>
+
+  <main class="container">
+    <section class="summary">
+      <form action="/upload" method="post" enctype="multipart/form-data">
+        <strong>Upload a scan:</strong>
+        <input type="file" name="file" accept=".xml,application/xml,text/xml" required/>
+        <button type="submit" class="btn small">Upload &amp; view report</button>
+      </form>
+      <p class="muted">Or from the command line: <code>curl -F file=@scan.xml http://{{.Host}}/upload -o report.html</code></p>
+    </section>
+
+    {{if .Scans}}
+    <div class="ports-table-wrap">
+      <table class="ports-table">
+        <thead><tr><th>Uploaded</th><th>File</th><th>Scan started</th><th>Hosts up</th><th>Open ports</th><th>Command line</th><th></th></tr></thead>
+        <tbody>
+          {{range .Scans}}
+          <tr>
+            <td>{{.Uploaded.Format "2006-01-02 15:04"}} UTC</td>
+            <td><a href="/scans/{{.ID}}">{{.Name}}</a></td>
+            <td>{{or .StartStr "-"}}</td>
+            <td>{{.Up}} / {{.Hosts}}</td>
+            <td>{{.OpenPorts}}</td>
+            <td><code style="font-size:12px;color:var(--muted);">{{.Args}}</code></td>
+            <td><a href="/scans/{{.ID}}.xml" class="muted">XML</a></td>
+          </tr>
+          {{end}}
+        </tbody>
+      </table>
+    </div>
+    {{else}}
+    <p class="text-center muted">No scans uploaded yet.</p>
+    {{end}}
+
+    <footer class="footer">
+      <small class="muted">🛡️ Nmap HTML Converter v{{.Version}} by <strong>DefenceLogic.io</strong></small>
+    </footer>
+  </main>
+</body>
+</html>
+`))
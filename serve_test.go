@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	tpl, err := loadTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	s := &scanServer{store: &scanStore{dir: t.TempDir()}, tpl: tpl, maxUpload: 1 << 20}
+	srv := httptest.NewServer(s.routes())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// noRedirect lets the tests see 303 answers
+var noRedirect = &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+func TestServeUpload(t *testing.T) {
+	srv := newTestServer(t)
+	post := func(body io.Reader, contentType string) *http.Response {
+		t.Helper()
+		resp, err := noRedirect.Post(srv.URL+"/upload?name=scan.xml", contentType, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	first := post(strings.NewReader(testScanXML), "application/xml")
+	if first.StatusCode != http.StatusCreated {
+		t.Fatalf("upload: status %d, want 201", first.StatusCode)
+	}
+	location := first.Header.Get("Location")
+	if !strings.HasPrefix(location, "/scans/") {
+		t.Fatalf("upload: Location %q", location)
+	}
+
+	// the same file as a multipart form is a duplicate
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	fw, _ := mw.CreateFormFile("file", "again.xml")
+	fw.Write([]byte(testScanXML))
+	mw.Close()
+	dup := post(&form, mw.FormDataContentType())
+	if dup.StatusCode != http.StatusSeeOther || dup.Header.Get("Location") != location {
+		t.Errorf("duplicate: status %d, Location %q; want 303, %q", dup.StatusCode, dup.Header.Get("Location"), location)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"not nmap", "<html></html>", http.StatusBadRequest},
+		{"not XML", "hello", http.StatusBadRequest},
+		{"truncated", testScanXML[:200], http.StatusBadRequest},
+		{"too large", "<nmaprun>" + strings.Repeat(" ", 2<<20) + "</nmaprun>", http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		if resp := post(strings.NewReader(tt.body), "application/xml"); resp.StatusCode != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, resp.StatusCode, tt.want)
+		}
+	}
+}
+
+func TestServeIndexAndScans(t *testing.T) {
+	srv := newTestServer(t)
+	resp, err := noRedirect.Post(srv.URL+"/upload?name=scan.xml", "application/xml", strings.NewReader(testScanXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	location := resp.Header.Get("Location")
+
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(b)
+	}
+	tests := []struct {
+		path   string
+		status int
+		want   string
+	}{
+		{"/", http.StatusOK, "Nmap HTML Converter v" + appVersion},
+		{"/", http.StatusOK, location},
+		{location, http.StatusOK, "10.0.0.1"},
+		{location + ".xml", http.StatusOK, testScanXML},
+		{"/scans/20240101-000000-deadbeef", http.StatusNotFound, ""},
+		{"/scans/../../etc/passwd", http.StatusNotFound, ""},
+		{"/nope", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		status, body := get(tt.path)
+		if status != tt.status || !strings.Contains(body, tt.want) {
+			t.Errorf("GET %s: status %d, want %d containing %q", tt.path, status, tt.status, tt.want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	siemVendor  = "DefenceLogic"
+	siemProduct = "Nmap HTML Converter"
+)
+
+// siemEvent is one open port or one exposure finding, ready to be formatted
+// as CEF or LEEF
+type siemEvent struct {
+	SignatureID string
+	Name        string
+	Level       string
+	Score       int
+	Time        time.Time
+	Host        Host
+	Port        Port
+}
+
+// buildSIEMEvents emits an event for every open port plus one per port that
+// violates the exposure policy
+func buildSIEMEvents(info NmapRunInfo, hosts []Host, policy ExposurePolicy) []siemEvent {
+	at := info.Started()
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	var events []siemEvent
+	for _, h := range hosts {
+		for _, p := range h.OpenPorts() {
+			name := fmt.Sprintf("Open port %d/%s", p.PortId, p.Protocol)
+			if p.Service.Name != "" {
+				name += " " + p.Service.Name
+			}
+			events = append(events, siemEvent{
+				SignatureID: "nmap-open-port",
+				Name:        name,
+				Level:       p.Risk.Level,
+				Score:       p.Risk.Score,
+				Time:        at,
+				Host:        h,
+				Port:        p,
+			})
+		}
+		for _, check := range policy.Evaluate(h) {
+			for _, p := range check.Violations {
+				events = append(events, siemEvent{
+					SignatureID: "nmap-policy-violation",
+					Name:        "Exposure policy violation: " + check.Name,
+					Level:       p.Risk.Level,
+					Score:       p.Risk.Score,
+					Time:        at,
+					Host:        h,
+					Port:        p,
+				})
+			}
+		}
+	}
+	return events
+}
+
+// cefSeverity maps risk levels onto the 0-10 CEF/LEEF severity scale
+func cefSeverity(level string) int {
+	switch level {
+	case RiskCritical:
+		return 10
+	case RiskHigh:
+		return 8
+	case RiskMedium:
+		return 5
+	case RiskLow:
+		return 3
+	default:
+		return 1
+	}
+}
+
+// syslogSeverity maps risk levels onto RFC 5424 severities
+func syslogSeverity(level string) int {
+	switch level {
+	case RiskCritical:
+		return 2 // crit
+	case RiskHigh:
+		return 3 // err
+	case RiskMedium:
+		return 4 // warning
+	case RiskLow:
+		return 5 // notice
+	default:
+		return 6 // info
+	}
+}
+
+var (
+	cefHeaderEscaper = strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+	cefValueEscaper  = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+	leefValueEscaper = strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ")
+)
+
+// formatCEF renders an event as an ArcSight CEF:0 line
+func formatCEF(e siemEvent) string {
+	header := []string{
+		"CEF:0",
+		cefHeaderEscaper.Replace(siemVendor),
+		cefHeaderEscaper.Replace(siemProduct),
+		appVersion,
+		e.SignatureID,
+		cefHeaderEscaper.Replace(e.Name),
+		strconv.Itoa(cefSeverity(e.Level)),
+	}
+	ext := [][2]string{
+		{"rt", strconv.FormatInt(e.Time.UnixMilli(), 10)},
+		{"dst", e.Host.PrimaryAddr()},
+		{"dhost", e.Host.PrimaryName()},
+		{"dpt", strconv.Itoa(e.Port.PortId)},
+		{"proto", strings.ToUpper(e.Port.Protocol)},
+		{"app", e.Port.Service.Name},
+		{"cs1Label", "product"},
+		{"cs1", strings.TrimSpace(e.Port.Service.Product + " " + e.Port.Service.Version)},
+		{"cs2Label", "riskLevel"},
+		{"cs2", e.Level},
+		{"cn1Label", "riskScore"},
+		{"cn1", strconv.Itoa(e.Score)},
+	}
+	var parts []string
+	for _, kv := range ext {
+		if kv[1] != "" {
+			parts = append(parts, kv[0]+"="+cefValueEscaper.Replace(kv[1]))
+		}
+	}
+	return strings.Join(header, "|") + "|" + strings.Join(parts, " ")
+}
+
+// formatLEEF renders an event as an IBM QRadar LEEF:1.0 line (tab delimited)
+func formatLEEF(e siemEvent) string {
+	header := []string{
+		"LEEF:1.0",
+		siemVendor,
+		siemProduct,
+		appVersion,
+		e.SignatureID,
+	}
+	attrs := [][2]string{
+		{"devTime", strconv.FormatInt(e.Time.UnixMilli(), 10)},
+		{"devTimeFormat", "milliseconds"},
+		{"cat", e.Name},
+		{"sev", strconv.Itoa(cefSeverity(e.Level))},
+		{"dst", e.Host.PrimaryAddr()},
+		{"dstName", e.Host.PrimaryName()},
+		{"dstPort", strconv.Itoa(e.Port.PortId)},
+		{"proto", strings.ToUpper(e.Port.Protocol)},
+		{"service", e.Port.Service.Name},
+		{"product", strings.TrimSpace(e.Port.Service.Product + " " + e.Port.Service.Version)},
+		{"riskLevel", e.Level},
+		{"riskScore", strconv.Itoa(e.Score)},
+	}
+	var parts []string
+	for _, kv := range attrs {
+		if kv[1] != "" {
+			parts = append(parts, kv[0]+"="+leefValueEscaper.Replace(kv[1]))
+		}
+	}
+	return strings.Join(header, "|") + "|" + strings.Join(parts, "\t")
+}
+
+func formatSIEMEvent(format string, e siemEvent) string {
+	if format == "leef" {
+		return formatLEEF(e)
+	}
+	return formatCEF(e)
+}
+
+// writeSIEMEvents writes one formatted event per line
+func writeSIEMEvents(w io.Writer, format string, events []siemEvent) error {
+	for _, e := range events {
+		if _, err := io.WriteString(w, formatSIEMEvent(format, e)+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendSyslog delivers events to a udp:// or tcp:// syslog target using
+// RFC 3164 framing (facility local4), newline-terminated over TCP
+func sendSyslog(target, format string, events []siemEvent) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return fmt.Errorf("parse syslog target: %w", err)
+	}
+	if u.Scheme != "udp" && u.Scheme != "tcp" {
+		return fmt.Errorf("syslog target must be udp://host:port or tcp://host:port, got %q", target)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "514")
+	}
+
+	conn, err := net.DialTimeout(u.Scheme, addr, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+	const facility = 20 // local4
+	for _, e := range events {
+		pri := facility*8 + syslogSeverity(e.Level)
+		msg := fmt.Sprintf("<%d>%s %s nmap-converter: %s", pri, time.Now().Format(time.Stamp), hostname, formatSIEMEvent(format, e))
+		if u.Scheme == "tcp" {
+			msg += "\n"
+		}
+		if _, err := conn.Write([]byte(msg)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCEFEscaping(t *testing.T) {
+	tests := []struct {
+		name string
+		ev   siemEvent
+		want string
+	}{
+		{
+			name: "header pipe and backslash",
+			ev:   siemEvent{SignatureID: "sig", Name: `a|b\c`},
+			want: `|sig|a\|b\\c|1|`,
+		},
+		{
+			name: "extension equals and backslash",
+			ev:   siemEvent{Port: Port{Service: Service{Product: `x=y\z`}}},
+			want: `cs1=x\=y\\z`,
+		},
+		{
+			name: "extension newlines",
+			ev:   siemEvent{Port: Port{Service: Service{Product: "a\r\nb\nc"}}},
+			want: `cs1=a\nb\nc`,
+		},
+		{
+			name: "pipe is literal in extensions",
+			ev:   siemEvent{Port: Port{Service: Service{Product: "a|b"}}},
+			want: "cs1=a|b",
+		},
+	}
+	for _, tt := range tests {
+		got := formatCEF(tt.ev)
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("%s: %q does not contain %q", tt.name, got, tt.want)
+		}
+		if strings.ContainsAny(got, "\r\n") {
+			t.Errorf("%s: %q spans several lines", tt.name, got)
+		}
+	}
+}
+
+func TestLEEFEscaping(t *testing.T) {
+	ev := siemEvent{
+		SignatureID: "nmap-open-port",
+		Name:        "Open port 22/tcp ssh",
+		Level:       RiskHigh,
+		Host:        testHost("10.0.0.1", "up"),
+		Port:        Port{PortId: 22, Protocol: "tcp", Service: Service{Name: "ssh", Product: "Open\tSSH\r\n7.4"}},
+	}
+	got := formatLEEF(ev)
+	if !strings.HasPrefix(got, "LEEF:1.0|"+siemVendor+"|"+siemProduct+"|"+appVersion+"|nmap-open-port|") {
+		t.Errorf("unexpected LEEF header: %q", got)
+	}
+	_, attrs, _ := strings.Cut(got, "|nmap-open-port|")
+	fields := strings.Split(attrs, "\t")
+	want := map[string]string{"sev": "8", "dst": "10.0.0.1", "dstPort": "22", "proto": "TCP", "product": "Open SSH 7.4"}
+	seen := map[string]string{}
+	for _, f := range fields {
+		k, v, ok := strings.Cut(f, "=")
+		if !ok {
+			t.Errorf("attribute %q has no key", f)
+		}
+		seen[k] = v
+	}
+	for k, v := range want {
+		if seen[k] != v {
+			t.Errorf("%s = %q, want %q", k, seen[k], v)
+		}
+	}
+	if strings.ContainsAny(got, "\r\n") {
+		t.Errorf("%q spans several lines", got)
+	}
+}
+
+func TestSIEMSeverity(t *testing.T) {
+	tests := []struct {
+		level       string
+		cef, syslog int
+	}{
+		{RiskCritical, 10, 2},
+		{RiskHigh, 8, 3},
+		{RiskMedium, 5, 4},
+		{RiskLow, 3, 5},
+		{"", 1, 6},
+	}
+	for _, tt := range tests {
+		if got := cefSeverity(tt.level); got != tt.cef {
+			t.Errorf("cefSeverity(%q) = %d, want %d", tt.level, got, tt.cef)
+		}
+		if got := syslogSeverity(tt.level); got != tt.syslog {
+			t.Errorf("syslogSeverity(%q) = %d, want %d", tt.level, got, tt.syslog)
+		}
+	}
+}
+
+func TestBuildSIEMEvents(t *testing.T) {
+	closed := testPort(80, "http")
+	closed.State.State = "closed"
+	hosts := []Host{
+		testHost("10.0.0.1", "up", testPort(22, "ssh"), testPort(23, "telnet"), closed),
+		testHost("10.0.0.2", "up", testPort(443, "https")),
+	}
+	policy := ExposurePolicy{DeniedServices: []string{"telnet"}, AllowedPorts: []PortSpec{{Port: 22}, {Port: 443}}}
+	events := buildSIEMEvents(NmapRunInfo{StartTime: "1700000000"}, hosts, policy)
+
+	count := map[string]int{}
+	for _, e := range events {
+		count[e.Host.PrimaryAddr()+" "+e.SignatureID]++
+		if !e.Time.Equal(time.Unix(1700000000, 0)) {
+			t.Errorf("event time %v, want the scan start", e.Time)
+		}
+	}
+	want := map[string]int{
+		// 22 and 23 open; telnet breaks both the denied service and the allowed ports checks
+		"10.0.0.1 nmap-open-port":        2,
+		"10.0.0.1 nmap-policy-violation": 2,
+		"10.0.0.2 nmap-open-port":        1,
+	}
+	for k, n := range want {
+		if count[k] != n {
+			t.Errorf("%s: %d events, want %d", k, count[k], n)
+		}
+	}
+	if len(events) != 5 {
+		t.Errorf("got %d events, want 5", len(events))
+	}
+}
+
+func TestSendSyslogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+
+	events := []siemEvent{
+		{SignatureID: "nmap-open-port", Name: "Open port 23/tcp telnet", Level: RiskHigh, Port: testPort(23, "telnet")},
+		{SignatureID: "nmap-open-port", Name: "Open port 22/tcp ssh", Level: RiskLow, Port: testPort(22, "ssh")},
+	}
+	if err := sendSyslog("udp://"+conn.LocalAddr().String(), "cef", events); err != nil {
+		t.Fatal(err)
+	}
+
+	// <PRI>Mmm dd hh:mm:ss hostname tag: message, facility local4 (20)
+	frame := regexp.MustCompile(`^<(\d+)>[A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2} \S+ nmap-converter: (CEF:0\|.*)$`)
+	wantPRI := []string{"163", "165"}
+	buf := make([]byte, 4096)
+	for i, e := range events {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := frame.FindStringSubmatch(string(buf[:n]))
+		if m == nil {
+			t.Fatalf("datagram %q is not RFC 3164 framed", buf[:n])
+		}
+		if m[1] != wantPRI[i] {
+			t.Errorf("PRI = %s, want %s", m[1], wantPRI[i])
+		}
+		if m[2] != formatCEF(e) {
+			t.Errorf("message = %q, want %q", m[2], formatCEF(e))
+		}
+	}
+}
+
+func TestSendSyslogBadTarget(t *testing.T) {
+	for _, target := range []string{"siem:514", "http://siem:514"} {
+		if err := sendSyslog(target, "cef", nil); err == nil {
+			t.Errorf("sendSyslog(%q) succeeded", target)
+		}
+	}
+}
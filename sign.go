@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// manifestMarker opens the signature block appended to a signed report. The
+// block is an HTML comment after the report, so browsers ignore it.
+const manifestMarker = "\n<!-- nmap-html-converter manifest\n"
+
+// manifestEnd closes the block and ends the file
+const manifestEnd = "\n-->\n"
+
+// Manifest describes a signed report. ReportSHA256 covers every byte of the
+// file before the signature block.
+type Manifest struct {
+	Generator    string `json:"generator"`
+	Version      string `json:"version"`
+	Created      string `json:"created"` // RFC 3339, UTC
+	SourceSHA256 string `json:"source_sha256"`
+	ReportSHA256 string `json:"report_sha256"`
+	KeySHA256    string `json:"key_sha256"` // identifies the signing key
+}
+
+// signedManifest is the JSON in the signature block. The signature covers
+// the exact manifest bytes as written.
+type signedManifest struct {
+	Manifest  json.RawMessage `json:"manifest"`
+	Signature string          `json:"signature"` // base64 Ed25519
+}
+
+// loadSigningKey reads a PEM PKCS#8 Ed25519 private key, as written by
+// "openssl genpkey -algorithm ed25519"
+func loadSigningKey(filename string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(filename, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an Ed25519 key", filename)
+	}
+	return priv, nil
+}
+
+// loadVerifyKey reads a PEM PKIX Ed25519 public key, as written by
+// "openssl pkey -pubout"
+func loadVerifyKey(filename string) (ed25519.PublicKey, error) {
+	der, err := readPEM(filename, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an Ed25519 key", filename)
+	}
+	return pub, nil
+}
+
+func readPEM(filename, blockType string) ([]byte, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			return nil, fmt.Errorf("%s: no %q PEM block", filename, blockType)
+		}
+		if block.Type == blockType {
+			return block.Bytes, nil
+		}
+	}
+}
+
+// keyID is the hex SHA-256 of the public key
+func keyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeManifest appends the signed manifest for a report whose bytes
+// hashed to reportSum
+func writeManifest(w io.Writer, key ed25519.PrivateKey, sourceSum, reportSum []byte) error {
+	m, err := json.Marshal(Manifest{
+		Generator:    "nmap-html-converter",
+		Version:      appVersion,
+		Created:      time.Now().UTC().Format(time.RFC3339),
+		SourceSHA256: hex.EncodeToString(sourceSum),
+		ReportSHA256: hex.EncodeToString(reportSum),
+		KeySHA256:    keyID(key.Public().(ed25519.PublicKey)),
+	})
+	if err != nil {
+		return err
+	}
+	block, err := json.Marshal(signedManifest{
+		Manifest:  m,
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, m)),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s%s%s", manifestMarker, block, manifestEnd)
+	return err
+}
+
+// verifyReport checks the signature and the report hash, and the source
+// hash when the original XML is given
+func verifyReport(report []byte, pub ed25519.PublicKey, source []byte) (Manifest, error) {
+	var m Manifest
+	i := bytes.LastIndex(report, []byte(manifestMarker))
+	if i < 0 {
+		return m, errors.New("no signed manifest in report")
+	}
+	// the manifest must be the exact tail of the file: anything appended
+	// after it would be covered by neither hash
+	rest := report[i+len(manifestMarker):]
+	block, ok := bytes.CutSuffix(rest, []byte(manifestEnd))
+	if !ok {
+		if bytes.Contains(rest, []byte("\n-->")) {
+			return m, errors.New("content after the manifest: the report was altered after signing")
+		}
+		return m, errors.New("truncated manifest")
+	}
+	var sm signedManifest
+	if err := json.Unmarshal(block, &sm); err != nil {
+		return m, fmt.Errorf("parse manifest: %w", err)
+	}
+	if err := json.Unmarshal(sm.Manifest, &m); err != nil {
+		return m, fmt.Errorf("parse manifest: %w", err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(sm.Signature)
+	if err != nil {
+		return m, fmt.Errorf("parse signature: %w", err)
+	}
+	if m.KeySHA256 != keyID(pub) {
+		return m, fmt.Errorf("signed with a different key (%s)", m.KeySHA256)
+	}
+	if !ed25519.Verify(pub, sm.Manifest, sig) {
+		return m, errors.New("bad signature: the manifest was altered")
+	}
+	sum := sha256.Sum256(report[:i])
+	if hex.EncodeToString(sum[:]) != m.ReportSHA256 {
+		return m, errors.New("report hash mismatch: the report was altered after signing")
+	}
+	if source != nil {
+		sum := sha256.Sum256(source)
+		if hex.EncodeToString(sum[:]) != m.SourceSHA256 {
+			return m, errors.New("source XML hash mismatch: the report was not generated from this file")
+		}
+	}
+	return m, nil
+}
+
+// runVerify is the verify subcommand:
+//
+//	nmap-html-converter verify -key report.pub [-xml scan.xml] report.html
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	var keyPath, xmlPath string
+	fs.StringVar(&keyPath, "key", "", "Ed25519 public key (PEM) to verify against")
+	fs.StringVar(&xmlPath, "xml", "", "original nmap XML to check the report was generated from (optional)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s verify -key report.pub [-xml scan.xml] report.html\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if keyPath == "" || fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	pub, err := loadVerifyKey(keyPath)
+	if err != nil {
+		return err
+	}
+	report, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var source []byte
+	if xmlPath != "" {
+		if source, err = os.ReadFile(xmlPath); err != nil {
+			return err
+		}
+	}
+	m, err := verifyReport(report, pub, source)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("OK: %s is signed by key %s\n", fs.Arg(0), m.KeySHA256)
+	fmt.Printf("  generator:  %s v%s\n", m.Generator, m.Version)
+	fmt.Printf("  created:    %s\n", m.Created)
+	fmt.Printf("  source xml: sha256 %s", m.SourceSHA256)
+	if source != nil {
+		fmt.Printf(" (matches %s)", xmlPath)
+	}
+	fmt.Println()
+	return nil
+}
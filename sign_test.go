@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// signedTestReport returns a report signed with key, as main writes it
+func signedTestReport(t *testing.T, key ed25519.PrivateKey, report, source []byte) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	out.Write(report)
+	sourceSum, reportSum := sha256.Sum256(source), sha256.Sum256(report)
+	if err := writeManifest(&out, key, sourceSum[:], reportSum[:]); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestVerifyReport(t *testing.T) {
+	pub, key, _ := ed25519.GenerateKey(rand.Reader)
+	otherPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	source := []byte(testScanXML)
+	report := []byte("<html><body>10.0.0.1</body></html>\n")
+	signed := signedTestReport(t, key, report, source)
+
+	replace := func(old, new string) []byte {
+		return bytes.Replace(signed, []byte(old), []byte(new), 1)
+	}
+	i := bytes.LastIndex(signed, []byte(manifestMarker))
+	tests := []struct {
+		name    string
+		report  []byte
+		pub     ed25519.PublicKey
+		source  []byte
+		wantErr string // "" for a valid report
+	}{
+		{"valid", signed, pub, nil, ""},
+		{"valid with source", signed, pub, source, ""},
+		{"report altered", replace("10.0.0.1", "10.0.0.2"), pub, nil, "report hash mismatch"},
+		{"content appended", append(append([]byte(nil), signed...), "<script>alert(1)</script>\n"...), pub, nil, "content after the manifest"},
+		{"comment appended", append(append([]byte(nil), signed...), "<!-- x\n-->\n"...), pub, nil, "parse manifest"},
+		{"manifest altered", replace(`"generator":"nmap-html-converter"`, `"generator":"nmap-html-convertor"`), pub, nil, "bad signature"},
+		{"other key", signed, otherPub, nil, "different key"},
+		{"other source", signed, pub, []byte("<nmaprun/>"), "source XML hash mismatch"},
+		{"truncated", signed[:len(signed)-10], pub, nil, "truncated manifest"},
+		{"unsigned", report, pub, nil, "no signed manifest"},
+		{"re-signed tail", append(append([]byte(nil), signed[:i]...), signedTestReport(t, key, []byte("x"), source)...), pub, nil, "report hash mismatch"},
+	}
+	for _, tt := range tests {
+		m, err := verifyReport(tt.report, tt.pub, tt.source)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.wantErr == "" && m.KeySHA256 != keyID(pub):
+			t.Errorf("%s: key %s, want %s", tt.name, m.KeySHA256, keyID(pub))
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	pub, key, _ := ed25519.GenerateKey(rand.Reader)
+	dir := t.TempDir()
+	writePEM := func(name, blockType string, der []byte) string {
+		file := filepath.Join(dir, name)
+		os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600)
+		return file
+	}
+	privDER, _ := x509.MarshalPKCS8PrivateKey(key)
+	pubDER, _ := x509.MarshalPKIXPublicKey(pub)
+	privFile := writePEM("k.pem", "PRIVATE KEY", privDER)
+	pubFile := writePEM("k.pub", "PUBLIC KEY", pubDER)
+
+	gotKey, err := loadSigningKey(privFile)
+	if err != nil || !gotKey.Equal(key) {
+		t.Errorf("loadSigningKey: %v", err)
+	}
+	gotPub, err := loadVerifyKey(pubFile)
+	if err != nil || !gotPub.Equal(pub) {
+		t.Errorf("loadVerifyKey: %v", err)
+	}
+	if _, err := loadSigningKey(pubFile); err == nil {
+		t.Error("public key accepted as a signing key")
+	}
+	if _, err := loadVerifyKey(privFile); err == nil {
+		t.Error("private key accepted as a verify key")
+	}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// WindowsInfo is the Windows/SMB posture assembled from the smb-* scripts,
+// on ports and in <hostscript>
+type WindowsInfo struct {
+	OS           string    `json:"os,omitempty"`
+	ComputerName string    `json:"computer_name,omitempty"`
+	NetBIOSName  string    `json:"netbios_name,omitempty"`
+	Domain       string    `json:"domain,omitempty"`
+	Forest       string    `json:"forest,omitempty"`
+	FQDN         string    `json:"fqdn,omitempty"`
+	Workgroup    string    `json:"workgroup,omitempty"`
+	Signing      string    `json:"signing,omitempty"` // required, enabled, disabled; "" when unknown
+	SMBv1        string    `json:"smbv1,omitempty"`   // yes, no; "" when smb-protocols did not run
+	Dialects     []string  `json:"dialects,omitempty"`
+	Vulns        []SMBVuln `json:"vulnerabilities,omitempty"`
+}
+
+// SMBVuln is a smb-vuln-* script that reported the host vulnerable
+type SMBVuln struct {
+	Script string   `json:"script"`
+	Title  string   `json:"title"`
+	State  string   `json:"state"`
+	CVEs   []string `json:"cves,omitempty"`
+}
+
+// Signing levels, weakest first so the worst reported one can be kept
+var signingRank = map[string]int{"disabled": 1, "enabled": 2, "required": 3}
+
+// setSigning keeps the weakest signing level seen across SMB1 and SMB2 results
+func (w *WindowsInfo) setSigning(level string) {
+	if level == "" {
+		return
+	}
+	if w.Signing == "" || signingRank[level] < signingRank[w.Signing] {
+		w.Signing = level
+	}
+}
+
+// signingLevel maps script wording onto required/enabled/disabled
+func signingLevel(s string) string {
+	s = strings.ToLower(s)
+	switch {
+	case strings.Contains(s, "not required"), strings.Contains(s, "supported"):
+		return "enabled"
+	case strings.Contains(s, "required"):
+		return "required"
+	case strings.Contains(s, "disabled"):
+		return "disabled"
+	case strings.Contains(s, "enabled"):
+		return "enabled"
+	}
+	return ""
+}
+
+// hostScripts returns the host-level scripts followed by every port script
+func hostScripts(h Host) []Script {
+	scripts := append([]Script(nil), h.HostScripts...)
+	for _, p := range h.Ports.Ports {
+		scripts = append(scripts, p.Scripts...)
+	}
+	return scripts
+}
+
+// analyzeWindows builds the posture panel, nil when no SMB script ran
+func analyzeWindows(h Host) *WindowsInfo {
+	var w WindowsInfo
+	found := false
+	for _, s := range hostScripts(h) {
+		switch {
+		case s.ID == "smb-os-discovery":
+			found = true
+			parseOSDiscovery(s, &w)
+		case s.ID == "smb-security-mode":
+			found = true
+			if v := s.root().elem("message_signing"); v != "" {
+				w.setSigning(signingLevel(v))
+			}
+			for _, line := range strings.Split(s.Output, "\n") {
+				if k, v, ok := strings.Cut(trimScriptLine(line), ":"); ok && strings.TrimSpace(k) == "message_signing" {
+					w.setSigning(signingLevel(v))
+				}
+			}
+		case s.ID == "smb2-security-mode":
+			found = true
+			for _, t := range s.Tables {
+				for _, e := range t.Elems {
+					w.setSigning(signingLevel(e.Value))
+				}
+			}
+			for _, line := range strings.Split(s.Output, "\n") {
+				if strings.Contains(strings.ToLower(line), "signing") {
+					w.setSigning(signingLevel(line))
+				}
+			}
+		case s.ID == "smb-protocols":
+			found = true
+			parseSMBProtocols(s, &w)
+		case strings.HasPrefix(s.ID, "smb-vuln-") || strings.HasPrefix(s.ID, "smb2-vuln-"):
+			found = true
+			w.Vulns = append(w.Vulns, parseSMBVulns(s)...)
+		}
+	}
+	if !found {
+		return nil
+	}
+	return &w
+}
+
+// parseOSDiscovery reads the structured elems or the "Key: value" text
+func parseOSDiscovery(s Script, w *WindowsInfo) {
+	clean := func(v string) string {
+		return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(v), `\x00`))
+	}
+	set := func(key, value string) {
+		value = clean(value)
+		if value == "" {
+			return
+		}
+		switch strings.ToLower(key) {
+		case "os":
+			w.OS = value
+		case "computer name":
+			w.ComputerName = value
+		case "server", "netbios computer name":
+			w.NetBIOSName = value
+		case "domain_dns", "domain name":
+			w.Domain = value
+		case "forest_dns", "forest name":
+			w.Forest = value
+		case "fqdn":
+			w.FQDN = value
+		case "workgroup", "netbios domain name":
+			if w.Workgroup == "" {
+				w.Workgroup = value
+			}
+		}
+	}
+	for _, e := range s.Elems {
+		set(e.Key, e.Value)
+	}
+	if len(s.Elems) > 0 {
+		return
+	}
+	for _, line := range strings.Split(s.Output, "\n") {
+		if k, v, ok := strings.Cut(trimScriptLine(line), ":"); ok {
+			set(strings.TrimSpace(k), v)
+		}
+	}
+}
+
+// parseSMBProtocols collects the dialects; SMBv1 shows up as "NT LM 0.12 (SMBv1)"
+func parseSMBProtocols(s Script, w *WindowsInfo) {
+	var dialects []string
+	if t, ok := s.root().table("dialects"); ok {
+		for _, e := range t.Elems {
+			dialects = append(dialects, strings.TrimSpace(e.Value))
+		}
+	} else {
+		in := false
+		for _, line := range strings.Split(s.Output, "\n") {
+			line = trimScriptLine(line)
+			switch {
+			case line == "dialects:":
+				in = true
+			case in && line != "":
+				dialects = append(dialects, line)
+			}
+		}
+	}
+	w.Dialects = dialects
+	w.SMBv1 = "no"
+	for _, d := range dialects {
+		if strings.Contains(d, "SMBv1") || strings.HasPrefix(d, "NT LM 0.12") {
+			w.SMBv1 = "yes"
+		}
+	}
+}
+
+// parseSMBVulns returns the vulnerabilities the script confirmed. Structured
+// output has one table per vulnerability; text output is treated as one.
+func parseSMBVulns(s Script) []SMBVuln {
+	var out []SMBVuln
+	for _, t := range s.Tables {
+		state := t.elem("state")
+		if !strings.Contains(strings.ToUpper(state), "VULNERABLE") || strings.Contains(strings.ToUpper(state), "NOT VULNERABLE") {
+			continue
+		}
+		v := SMBVuln{Script: s.ID, Title: t.elem("title"), State: state}
+		if ids, ok := t.table("ids"); ok {
+			for _, e := range ids.Elems {
+				v.CVEs = append(v.CVEs, cvePattern.FindAllString(e.Value, -1)...)
+			}
+		}
+		if v.Title == "" {
+			v.Title = s.ID
+		}
+		out = append(out, v)
+	}
+	if len(s.Tables) > 0 || !scriptFlagsVulnerable(s) {
+		return out
+	}
+
+	v := SMBVuln{Script: s.ID, Title: s.ID, State: "VULNERABLE"}
+	lines := strings.Split(s.Output, "\n")
+	for i, line := range lines {
+		line = trimScriptLine(line)
+		if strings.HasPrefix(line, "State:") {
+			v.State = strings.TrimSpace(strings.TrimPrefix(line, "State:"))
+			// the title is the line before the state
+			if i > 0 {
+				if title := trimScriptLine(lines[i-1]); title != "" && title != "VULNERABLE:" {
+					v.Title = title
+				}
+			}
+		}
+	}
+	v.CVEs = uniqueSorted(cvePattern.FindAllString(s.Output, -1))
+	return append(out, v)
+}
+
+// uniqueSorted drops duplicates, e.g. a CVE named in both the IDs line and
+// the references
+func uniqueSorted(list []string) []string {
+	seen := make(map[string]bool, len(list))
+	var out []string
+	for _, s := range list {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// IsWindows reports whether the host is known to run Windows, from SMB OS
+// discovery, OS detection or the service fingerprints
+func (h Host) IsWindows() bool {
+	if h.Windows != nil && strings.Contains(strings.ToLower(h.Windows.OS), "windows") {
+		return true
+	}
+	if len(h.OS.Matches) > 0 {
+		for _, c := range h.OS.Matches[0].Classes {
+			if strings.EqualFold(c.Family, "windows") {
+				return true
+			}
+		}
+	}
+	for _, p := range h.Ports.Ports {
+		if strings.EqualFold(p.Service.OSType, "windows") {
+			return true
+		}
+		for _, cpe := range p.Service.CPEs {
+			if strings.HasPrefix(cpe, "cpe:/o:microsoft:windows") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// windowsFindings turns the posture into host-level findings
+func windowsFindings(w *WindowsInfo) []Finding {
+	if w == nil {
+		return nil
+	}
+	var out []Finding
+	if w.SMBv1 == "yes" {
+		out = append(out, Finding{RuleID: "smbv1-enabled", Title: "SMBv1 enabled", Level: RiskHigh, Score: 30,
+			Description: "SMBv1 is deprecated and was the vector for WannaCry and NotPetya.",
+			Remediation: "Disable SMBv1 (Set-SmbServerConfiguration -EnableSMB1Protocol $false)."})
+	}
+	if w.Signing == "enabled" || w.Signing == "disabled" {
+		out = append(out, Finding{RuleID: "smb-signing-not-required", Title: "SMB signing not required", Level: RiskMedium, Score: 15,
+			Description: "Without mandatory signing, SMB sessions can be relayed (NTLM relay).",
+			Remediation: "Require SMB signing through Group Policy."})
+	}
+	for _, v := range w.Vulns {
+		desc := "Confirmed by " + v.Script + "."
+		if len(v.CVEs) > 0 {
+			desc = "Confirmed by " + v.Script + " (" + strings.Join(v.CVEs, ", ") + ")."
+		}
+		out = append(out, Finding{RuleID: v.Script, Title: v.Title, Level: RiskCritical, Score: 50,
+			Description: desc, Remediation: "Apply the Microsoft security update for this vulnerability."})
+	}
+	return out
+}
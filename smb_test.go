@@ -0,0 +1,112 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzeWindowsText(t *testing.T) {
+	h := testHost("10.0.0.5", "up", testPort(445, "microsoft-ds"))
+	h.HostScripts = []Script{
+		{ID: "smb-os-discovery", Output: `
+  OS: Windows Server 2008 R2 Standard 7601 Service Pack 1 (Windows Server 2008 R2 Standard 6.1)
+  Computer name: dc01
+  NetBIOS computer name: DC01\x00
+  Domain name: corp.example
+  Forest name: corp.example
+  FQDN: dc01.corp.example
+  Workgroup: CORP\x00`},
+		{ID: "smb-security-mode", Output: `
+  account_used: guest
+  authentication_level: user
+  message_signing: required`},
+		{ID: "smb2-security-mode", Output: `
+  2:1:0:
+    Message signing enabled but not required`},
+		{ID: "smb-protocols", Output: `
+  dialects:
+    NT LM 0.12 (SMBv1) [dangerous, but default]
+    2:0:2
+    2:1:0`},
+		{ID: "smb-vuln-ms17-010", Output: `
+  VULNERABLE:
+  Remote Code Execution vulnerability in Microsoft SMBv1 servers (ms17-010)
+    State: VULNERABLE
+    IDs:  CVE:CVE-2017-0143
+    Risk factor: HIGH`},
+		{ID: "smb-vuln-ms10-054", Output: "false"},
+	}
+	w := analyzeWindows(h)
+	if w == nil {
+		t.Fatal("no Windows info")
+	}
+	want := WindowsInfo{
+		OS:           "Windows Server 2008 R2 Standard 7601 Service Pack 1 (Windows Server 2008 R2 Standard 6.1)",
+		ComputerName: "dc01",
+		NetBIOSName:  "DC01",
+		Domain:       "corp.example",
+		Forest:       "corp.example",
+		FQDN:         "dc01.corp.example",
+		Workgroup:    "CORP",
+		Signing:      "enabled", // the weaker of SMB1 required and SMB2 not required
+		SMBv1:        "yes",
+		Dialects:     []string{"NT LM 0.12 (SMBv1) [dangerous, but default]", "2:0:2", "2:1:0"},
+		Vulns: []SMBVuln{{
+			Script: "smb-vuln-ms17-010",
+			Title:  "Remote Code Execution vulnerability in Microsoft SMBv1 servers (ms17-010)",
+			State:  "VULNERABLE",
+			CVEs:   []string{"CVE-2017-0143"},
+		}},
+	}
+	if !reflect.DeepEqual(*w, want) {
+		t.Errorf("got %+v\nwant %+v", *w, want)
+	}
+
+	var ids []string
+	for _, f := range windowsFindings(w) {
+		ids = append(ids, f.RuleID)
+	}
+	if want := []string{"smbv1-enabled", "smb-signing-not-required", "smb-vuln-ms17-010"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("findings = %v, want %v", ids, want)
+	}
+}
+
+func TestAnalyzeWindowsTables(t *testing.T) {
+	p := testPort(445, "microsoft-ds")
+	p.Scripts = []Script{
+		{ID: "smb-protocols", Tables: []ScriptTable{{Key: "dialects", Elems: []ScriptElem{{Value: "2:1:0"}, {Value: "3:1:1"}}}}},
+		{ID: "smb2-security-mode", Tables: []ScriptTable{{Key: "3:1:1", Elems: []ScriptElem{{Value: "Message signing enabled and required"}}}}},
+		{ID: "smb-vuln-ms17-010", Tables: []ScriptTable{
+			{Key: "CVE-2017-0143", Elems: []ScriptElem{{Key: "title", Value: "MS17-010"}, {Key: "state", Value: "NOT VULNERABLE"}}},
+		}},
+	}
+	w := analyzeWindows(testHost("10.0.0.6", "up", p))
+	if w == nil {
+		t.Fatal("no Windows info")
+	}
+	if w.SMBv1 != "no" || w.Signing != "required" || len(w.Vulns) != 0 {
+		t.Errorf("smbv1 = %q, signing = %q, vulns = %+v", w.SMBv1, w.Signing, w.Vulns)
+	}
+	if len(windowsFindings(w)) != 0 {
+		t.Errorf("findings = %+v, want none", windowsFindings(w))
+	}
+	if analyzeWindows(testHost("10.0.0.7", "up", testPort(22, "ssh"))) != nil {
+		t.Error("Windows info for a host without SMB scripts")
+	}
+}
+
+func TestSigningLevel(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"required", "required"},
+		{"Message signing enabled and required", "required"},
+		{"Message signing enabled but not required", "enabled"},
+		{"supported", "enabled"},
+		{"disabled (dangerous, but default)", "disabled"},
+		{"unknown", ""},
+	}
+	for _, tt := range tests {
+		if got := signingLevel(tt.in); got != tt.want {
+			t.Errorf("signingLevel(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	// pure Go driver keeps the binary self-contained (no cgo)
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema is created on first use; later runs are appended to the same tables
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id          INTEGER PRIMARY KEY,
+	source      TEXT,
+	scanner     TEXT,
+	args        TEXT,
+	start_time  INTEGER,
+	start_str   TEXT,
+	imported_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS hosts (
+	id            INTEGER PRIMARY KEY,
+	run_id        INTEGER NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+	status        TEXT,
+	status_reason TEXT
+);
+CREATE TABLE IF NOT EXISTS addresses (
+	id        INTEGER PRIMARY KEY,
+	host_id   INTEGER NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+	addr      TEXT NOT NULL,
+	addr_type TEXT
+);
+CREATE TABLE IF NOT EXISTS hostnames (
+	id      INTEGER PRIMARY KEY,
+	host_id INTEGER NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+	name    TEXT NOT NULL,
+	type    TEXT
+);
+CREATE TABLE IF NOT EXISTS ports (
+	id       INTEGER PRIMARY KEY,
+	host_id  INTEGER NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+	protocol TEXT,
+	port     INTEGER NOT NULL,
+	state    TEXT,
+	reason   TEXT
+);
+CREATE TABLE IF NOT EXISTS services (
+	id         INTEGER PRIMARY KEY,
+	port_id    INTEGER NOT NULL REFERENCES ports(id) ON DELETE CASCADE,
+	name       TEXT,
+	product    TEXT,
+	version    TEXT,
+	extra_info TEXT
+);
+CREATE TABLE IF NOT EXISTS scripts (
+	id        INTEGER PRIMARY KEY,
+	port_id   INTEGER NOT NULL REFERENCES ports(id) ON DELETE CASCADE,
+	script_id TEXT NOT NULL,
+	output    TEXT
+);
+CREATE TABLE IF NOT EXISTS host_scripts (
+	id        INTEGER PRIMARY KEY,
+	host_id   INTEGER NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+	script_id TEXT NOT NULL,
+	output    TEXT
+);
+CREATE TABLE IF NOT EXISTS cpes (
+	id         INTEGER PRIMARY KEY,
+	service_id INTEGER NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+	cpe        TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS findings (
+	id      INTEGER PRIMARY KEY,
+	port_id INTEGER NOT NULL REFERENCES ports(id) ON DELETE CASCADE,
+	rule_id TEXT NOT NULL,
+	title   TEXT,
+	level   TEXT,
+	score   INTEGER
+);
+CREATE TABLE IF NOT EXISTS host_findings (
+	id      INTEGER PRIMARY KEY,
+	host_id INTEGER NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+	rule_id TEXT NOT NULL,
+	title   TEXT,
+	level   TEXT,
+	score   INTEGER
+);
+CREATE TABLE IF NOT EXISTS cves (
+	id        INTEGER PRIMARY KEY,
+	port_id   INTEGER NOT NULL REFERENCES ports(id) ON DELETE CASCADE,
+	cve       TEXT NOT NULL,
+	cvss      REAL,
+	exploit   INTEGER NOT NULL DEFAULT 0,
+	potential INTEGER NOT NULL DEFAULT 0,
+	source    TEXT
+);
+CREATE TABLE IF NOT EXISTS host_cves (
+	id        INTEGER PRIMARY KEY,
+	host_id   INTEGER NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+	cve       TEXT NOT NULL,
+	cvss      REAL,
+	exploit   INTEGER NOT NULL DEFAULT 0,
+	source    TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_hosts_run ON hosts(run_id);
+CREATE INDEX IF NOT EXISTS idx_addresses_addr ON addresses(addr);
+CREATE INDEX IF NOT EXISTS idx_ports_host ON ports(host_id);
+CREATE INDEX IF NOT EXISTS idx_ports_port ON ports(port, protocol);
+CREATE INDEX IF NOT EXISTS idx_services_name ON services(name);
+CREATE INDEX IF NOT EXISTS idx_cves_cve ON cves(cve);
+CREATE INDEX IF NOT EXISTS idx_host_cves_cve ON host_cves(cve);
+`
+
+// sqliteMigrations add the columns introduced after a table was first
+// released. Migration i upgrades a database from user_version i to i+1.
+// sqliteSchema already has every column, so a new database only gets its
+// user_version set.
+var sqliteMigrations = []struct {
+	table, column, def string
+}{
+	{"cves", "potential", "INTEGER NOT NULL DEFAULT 0"},
+}
+
+// migrateSQLite brings a database written by an older release up to date
+func migrateSQLite(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	for i := version; i < len(sqliteMigrations); i++ {
+		m := sqliteMigrations[i]
+		ok, err := hasColumn(db, m.table, m.column)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, m.table, m.column, m.def)); err != nil {
+				return fmt.Errorf("add %s.%s: %w", m.table, m.column, err)
+			}
+		}
+	}
+	if version < len(sqliteMigrations) {
+		// PRAGMA does not take bound parameters
+		if _, err := db.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, len(sqliteMigrations))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hasColumn(db *sql.DB, table, column string) (bool, error) {
+	rows, err := db.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, typ        string
+			def              sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &def, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// openSQLite opens the database with foreign keys enforced. The pragma is
+// part of the connection string so that it applies to every pooled
+// connection, not just the one that happened to run the schema.
+func openSQLite(path string) (*sql.DB, error) {
+	return sql.Open("sqlite", path+"?_pragma=foreign_keys(1)")
+}
+
+// writeSQLite appends one scan run to the database at path, creating the
+// normalized schema if it does not exist yet
+func writeSQLite(path, source string, info NmapRunInfo, hosts []Host) error {
+	db, err := openSQLite(path)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return fmt.Errorf("create schema: %w", err)
+	}
+	if err := migrateSQLite(db); err != nil {
+		return fmt.Errorf("migrate schema: %w", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := insertRun(tx, source, info, hosts); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertRun(tx *sql.Tx, source string, info NmapRunInfo, hosts []Host) error {
+	var startTime interface{}
+	if started := info.Started(); !started.IsZero() {
+		startTime = started.Unix()
+	}
+	res, err := tx.Exec(`INSERT INTO runs (source, scanner, args, start_time, start_str, imported_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		source, info.Scanner, info.Args, startTime, info.StartStr, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("insert run: %w", err)
+	}
+	runID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, h := range hosts {
+		res, err := tx.Exec(`INSERT INTO hosts (run_id, status, status_reason) VALUES (?, ?, ?)`,
+			runID, h.Status.State, h.Status.Reason)
+		if err != nil {
+			return fmt.Errorf("insert host: %w", err)
+		}
+		hostID, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		for _, a := range h.Addresses {
+			if _, err := tx.Exec(`INSERT INTO addresses (host_id, addr, addr_type) VALUES (?, ?, ?)`,
+				hostID, a.Addr, a.AddrType); err != nil {
+				return fmt.Errorf("insert address: %w", err)
+			}
+		}
+		for _, n := range h.Hostnames.Names {
+			if _, err := tx.Exec(`INSERT INTO hostnames (host_id, name, type) VALUES (?, ?, ?)`,
+				hostID, n.Name, n.Type); err != nil {
+				return fmt.Errorf("insert hostname: %w", err)
+			}
+		}
+		for _, s := range h.HostScripts {
+			if _, err := tx.Exec(`INSERT INTO host_scripts (host_id, script_id, output) VALUES (?, ?, ?)`,
+				hostID, s.ID, s.Output); err != nil {
+				return fmt.Errorf("insert host script: %w", err)
+			}
+		}
+		for _, f := range h.Detected {
+			if _, err := tx.Exec(`INSERT INTO host_findings (host_id, rule_id, title, level, score) VALUES (?, ?, ?, ?, ?)`,
+				hostID, f.RuleID, f.Title, f.Level, f.Score); err != nil {
+				return fmt.Errorf("insert host finding: %w", err)
+			}
+		}
+		for _, c := range h.CVEs {
+			var cvss interface{}
+			if c.CVSS > 0 {
+				cvss = c.CVSS
+			}
+			if _, err := tx.Exec(`INSERT INTO host_cves (host_id, cve, cvss, exploit, source) VALUES (?, ?, ?, ?, ?)`,
+				hostID, c.ID, cvss, c.Exploit, c.Source); err != nil {
+				return fmt.Errorf("insert host cve: %w", err)
+			}
+		}
+		for _, p := range h.Ports.Ports {
+			if err := insertPort(tx, hostID, p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func insertPort(tx *sql.Tx, hostID int64, p Port) error {
+	res, err := tx.Exec(`INSERT INTO ports (host_id, protocol, port, state, reason) VALUES (?, ?, ?, ?, ?)`,
+		hostID, p.Protocol, p.PortId, p.State.State, p.State.Reason)
+	if err != nil {
+		return fmt.Errorf("insert port: %w", err)
+	}
+	portID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	res, err = tx.Exec(`INSERT INTO services (port_id, name, product, version, extra_info) VALUES (?, ?, ?, ?, ?)`,
+		portID, p.Service.Name, p.Service.Product, p.Service.Version, p.Service.Extras)
+	if err != nil {
+		return fmt.Errorf("insert service: %w", err)
+	}
+	serviceID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for _, cpe := range p.Service.CPEs {
+		if _, err := tx.Exec(`INSERT INTO cpes (service_id, cpe) VALUES (?, ?)`, serviceID, cpe); err != nil {
+			return fmt.Errorf("insert cpe: %w", err)
+		}
+	}
+
+	for _, s := range p.Scripts {
+		if _, err := tx.Exec(`INSERT INTO scripts (port_id, script_id, output) VALUES (?, ?, ?)`,
+			portID, s.ID, s.Output); err != nil {
+			return fmt.Errorf("insert script: %w", err)
+		}
+	}
+
+	for _, f := range p.Risk.Findings {
+		if _, err := tx.Exec(`INSERT INTO findings (port_id, rule_id, title, level, score) VALUES (?, ?, ?, ?, ?)`,
+			portID, f.RuleID, f.Title, f.Level, f.Score); err != nil {
+			return fmt.Errorf("insert finding: %w", err)
+		}
+	}
+
+	for _, c := range p.CVEs {
+		var cvss interface{}
+		if c.CVSS > 0 {
+			cvss = c.CVSS
+		}
+		if _, err := tx.Exec(`INSERT INTO cves (port_id, cve, cvss, exploit, potential, source) VALUES (?, ?, ?, ?, ?, ?)`,
+			portID, c.ID, cvss, c.Exploit, c.Potential, c.Source); err != nil {
+			return fmt.Errorf("insert cve: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func countRows(t *testing.T, db *sql.DB, table string) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil {
+		t.Fatalf("count %s: %v", table, err)
+	}
+	return n
+}
+
+func TestWriteSQLiteAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scans.db")
+	h := testHost("10.0.0.1", "up", testPort(22, "ssh"), testPort(445, "microsoft-ds"))
+	h.HostScripts = []Script{{ID: "smb-os-discovery", Output: "OS: Windows Server 2016"}}
+	h.CVEs = []CVE{{ID: "CVE-2017-0143", CVSS: 9.3, Source: "smb-vuln-ms17-010"}}
+	h.Detected = []Finding{{RuleID: "smb-signing-disabled", Title: "SMB signing not required", Level: RiskMedium, Score: 15}}
+	hosts := []Host{h, testHost("10.0.0.2", "down")}
+
+	for i := 0; i < 2; i++ {
+		if err := writeSQLite(path, "scan.xml", NmapRunInfo{Args: "nmap"}, hosts); err != nil {
+			t.Fatalf("run %d: %v", i+1, err)
+		}
+	}
+
+	db, err := openSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	tests := []struct {
+		table string
+		want  int
+	}{
+		{"runs", 2},
+		{"hosts", 4},
+		{"ports", 4},
+		{"host_scripts", 2},
+		{"host_cves", 2},
+		{"host_findings", 2},
+	}
+	for _, tt := range tests {
+		if got := countRows(t, db, tt.table); got != tt.want {
+			t.Errorf("%s rows = %d, want %d", tt.table, got, tt.want)
+		}
+	}
+}
+
+func TestWriteSQLiteMigratesOldSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// the cves table as released, before the potential column
+	old := strings.Replace(sqliteSchema, "\tpotential INTEGER NOT NULL DEFAULT 0,\n", "", 1)
+	if old == sqliteSchema {
+		t.Fatal("potential column not found in sqliteSchema")
+	}
+	if _, err := db.Exec(old); err != nil {
+		t.Fatal(err)
+	}
+
+	p := testPort(80, "http")
+	p.CVEs = []CVE{{ID: "CVE-2021-41773", CVSS: 7.5, Source: "nvd", Potential: true}}
+	hosts := []Host{testHost("10.0.0.1", "up", p)}
+	for i := 0; i < 2; i++ {
+		if err := writeSQLite(path, "scan.xml", NmapRunInfo{}, hosts); err != nil {
+			t.Fatalf("run %d: %v", i+1, err)
+		}
+	}
+
+	var potential, version int
+	if err := db.QueryRow(`SELECT SUM(potential) FROM cves`).Scan(&potential); err != nil {
+		t.Fatal(err)
+	}
+	if potential != 2 {
+		t.Errorf("potential CVEs = %d, want 2", potential)
+	}
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if version != len(sqliteMigrations) {
+		t.Errorf("user_version = %d, want %d", version, len(sqliteMigrations))
+	}
+}
+
+func TestOpenSQLiteCascades(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scans.db")
+	h := testHost("10.0.0.1", "up", testPort(22, "ssh"))
+	h.Detected = []Finding{{RuleID: "eol-os", Level: RiskHigh, Score: 30}}
+	if err := writeSQLite(path, "scan.xml", NmapRunInfo{}, []Host{h}); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := openSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// hold several connections at once so the pool cannot hand back the same one
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		var on int
+		if err := conn.QueryRowContext(ctx, `PRAGMA foreign_keys`).Scan(&on); err != nil {
+			t.Fatal(err)
+		}
+		if on != 1 {
+			t.Errorf("connection %d: foreign_keys = %d, want 1", i+1, on)
+		}
+	}
+
+	if _, err := db.Exec(`DELETE FROM runs`); err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{"hosts", "addresses", "ports", "services", "host_findings"} {
+		if n := countRows(t, db, table); n != 0 {
+			t.Errorf("%s: %d rows left after deleting the run", table, n)
+		}
+	}
+}
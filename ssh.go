@@ -0,0 +1,335 @@
+package main
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SSHInfo is the Go-side analysis of ssh2-enum-algos and ssh-hostkey output
+type SSHInfo struct {
+	Kex         []string      `json:"kex_algorithms,omitempty"`
+	HostKeyAlgs []string      `json:"server_host_key_algorithms,omitempty"`
+	Ciphers     []string      `json:"encryption_algorithms,omitempty"`
+	MACs        []string      `json:"mac_algorithms,omitempty"`
+	Compression []string      `json:"compression_algorithms,omitempty"`
+	HostKeys    []SSHHostKey  `json:"host_keys,omitempty"`
+	Weaknesses  []SSHWeakness `json:"weaknesses,omitempty"`
+}
+
+type SSHHostKey struct {
+	Type        string `json:"type"` // ssh-rsa, ecdsa-sha2-nistp256, ssh-ed25519 ...
+	Bits        int    `json:"bits,omitempty"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// Weak reports DSA keys and RSA keys shorter than 2048 bits
+func (k SSHHostKey) Weak() bool {
+	t := strings.ToLower(k.Type)
+	switch {
+	case strings.Contains(t, "dss") || (strings.Contains(t, "dsa") && !strings.Contains(t, "ecdsa")):
+		return true
+	case strings.Contains(t, "rsa"):
+		return k.Bits > 0 && k.Bits < 2048
+	}
+	return false
+}
+
+// SSHWeakness is one offered algorithm or host key that fails the policy
+type SSHWeakness struct {
+	Category  string `json:"category"` // kex, hostkey, cipher, mac, or key for the host keys themselves
+	Algorithm string `json:"algorithm"`
+	Reason    string `json:"reason"`
+}
+
+// sshPolicyRule flags algorithms matching a glob in one category
+type sshPolicyRule struct {
+	Category string
+	Pattern  string
+	Reason   string
+}
+
+// sshPolicy is the built-in algorithm policy
+var sshPolicy = []sshPolicyRule{
+	{"kex", "diffie-hellman-group1-sha1", "1024-bit Oakley group with SHA-1 (Logjam)"},
+	{"kex", "*-sha1", "key exchange using SHA-1"},
+	{"kex", "*-sha1-*", "key exchange using SHA-1"},
+	{"kex", "rsa1024-*", "1024-bit RSA key exchange"},
+	{"hostkey", "ssh-dss", "DSA host keys are limited to 1024 bits"},
+	{"cipher", "*-cbc", "CBC mode ciphers are vulnerable to plaintext recovery"},
+	{"cipher", "arcfour*", "RC4 is broken"},
+	{"cipher", "3des*", "64-bit block cipher (SWEET32)"},
+	{"cipher", "blowfish*", "64-bit block cipher (SWEET32)"},
+	{"cipher", "cast128*", "64-bit block cipher (SWEET32)"},
+	{"cipher", "none", "no encryption"},
+	{"mac", "hmac-md5*", "MD5 based MAC"},
+	{"mac", "*-96*", "truncated 96-bit MAC"},
+	{"mac", "umac-64*", "64-bit MAC tag"},
+	{"mac", "none", "no integrity protection"},
+}
+
+// algorithms returns the offered list for a policy category
+func (s *SSHInfo) algorithms(category string) []string {
+	switch category {
+	case "kex":
+		return s.Kex
+	case "hostkey":
+		return s.HostKeyAlgs
+	case "cipher":
+		return s.Ciphers
+	case "mac":
+		return s.MACs
+	}
+	return nil
+}
+
+func (s *SSHInfo) setAlgorithms(key string, algs []string) {
+	switch key {
+	case "kex_algorithms":
+		s.Kex = algs
+	case "server_host_key_algorithms":
+		s.HostKeyAlgs = algs
+	case "encryption_algorithms":
+		s.Ciphers = algs
+	case "mac_algorithms":
+		s.MACs = algs
+	case "compression_algorithms":
+		s.Compression = algs
+	}
+}
+
+// grade applies the policy; each algorithm is reported once, with the
+// first matching reason
+func (s *SSHInfo) grade() {
+	s.Weaknesses = nil
+	for _, category := range []string{"kex", "hostkey", "cipher", "mac"} {
+		for _, alg := range s.algorithms(category) {
+			for _, rule := range sshPolicy {
+				if rule.Category != category {
+					continue
+				}
+				if ok, _ := path.Match(rule.Pattern, alg); ok {
+					s.Weaknesses = append(s.Weaknesses, SSHWeakness{Category: category, Algorithm: alg, Reason: rule.Reason})
+					break
+				}
+			}
+		}
+	}
+	for _, k := range s.HostKeys {
+		if k.Weak() {
+			reason := "DSA host key"
+			if k.Bits > 0 && !strings.Contains(strings.ToLower(k.Type), "dss") {
+				reason = strconv.Itoa(k.Bits) + "-bit RSA host key"
+			}
+			s.Weaknesses = append(s.Weaknesses, SSHWeakness{Category: "key", Algorithm: k.Type, Reason: reason})
+		}
+	}
+}
+
+// WeakAlgorithms reports policy failures other than the host keys themselves
+func (s SSHInfo) WeakAlgorithms() bool {
+	for _, w := range s.Weaknesses {
+		if w.Category != "key" {
+			return true
+		}
+	}
+	return false
+}
+
+// WeakHostKey reports a DSA or short RSA host key
+func (s SSHInfo) WeakHostKey() bool {
+	for _, k := range s.HostKeys {
+		if k.Weak() {
+			return true
+		}
+	}
+	return false
+}
+
+// analyzeSSH builds the SSH audit for a port, nil when neither script ran
+func analyzeSSH(p Port) *SSHInfo {
+	var info SSHInfo
+	found := false
+	for _, s := range p.Scripts {
+		switch s.ID {
+		case "ssh2-enum-algos":
+			found = true
+			if len(s.Tables) > 0 {
+				for _, t := range s.Tables {
+					var algs []string
+					for _, e := range t.Elems {
+						algs = append(algs, strings.TrimSpace(e.Value))
+					}
+					info.setAlgorithms(t.Key, algs)
+				}
+			} else {
+				parseEnumAlgosText(s.Output, &info)
+			}
+		case "ssh-hostkey":
+			found = true
+			if len(s.Tables) > 0 {
+				for _, t := range s.Tables {
+					bits, _ := strconv.Atoi(t.elem("bits"))
+					info.HostKeys = append(info.HostKeys, SSHHostKey{
+						Type:        t.elem("type"),
+						Bits:        bits,
+						Fingerprint: normalizeFingerprint(t.elem("fingerprint")),
+					})
+				}
+			} else {
+				info.HostKeys = append(info.HostKeys, parseHostKeyText(s.Output)...)
+			}
+		}
+	}
+	if !found {
+		return nil
+	}
+	info.grade()
+	return &info
+}
+
+// parseEnumAlgosText reads the text layout:
+//
+//	kex_algorithms: (2)
+//	    curve25519-sha256
+//	    diffie-hellman-group1-sha1
+func parseEnumAlgosText(output string, info *SSHInfo) {
+	key := ""
+	var algs []string
+	flush := func() {
+		if key != "" {
+			info.setAlgorithms(key, algs)
+		}
+		algs = nil
+	}
+	for _, raw := range strings.Split(output, "\n") {
+		line := trimScriptLine(raw)
+		if line == "" {
+			continue
+		}
+		if name, _, ok := strings.Cut(line, ":"); ok && strings.HasSuffix(name, "_algorithms") {
+			flush()
+			key = name
+			continue
+		}
+		algs = append(algs, line)
+	}
+	flush()
+}
+
+// textKeyTypes maps the "(RSA)" suffix of the text output to key types
+var textKeyTypes = map[string]string{
+	"RSA":     "ssh-rsa",
+	"DSA":     "ssh-dss",
+	"ECDSA":   "ecdsa-sha2-nistp256",
+	"ED25519": "ssh-ed25519",
+}
+
+// parseHostKeyText reads lines like "2048 aa:bb:cc:... (RSA)"
+func parseHostKeyText(output string) []SSHHostKey {
+	var keys []SSHHostKey
+	for _, raw := range strings.Split(output, "\n") {
+		fields := strings.Fields(trimScriptLine(raw))
+		if len(fields) < 3 {
+			continue
+		}
+		bits, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		kind := strings.Trim(fields[len(fields)-1], "()")
+		keyType, ok := textKeyTypes[strings.ToUpper(kind)]
+		if !ok {
+			keyType = strings.ToLower(kind)
+		}
+		keys = append(keys, SSHHostKey{Type: keyType, Bits: bits, Fingerprint: normalizeFingerprint(fields[1])})
+	}
+	return keys
+}
+
+// normalizeFingerprint makes "AA:BB:.." and "aabb.." compare equal
+func normalizeFingerprint(fp string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(fp), ":", ""))
+}
+
+// sshKeyReuseFinding is reported for every host sharing a host key
+var sshKeyReuseFinding = Finding{
+	RuleID:      "ssh-hostkey-reuse",
+	Title:       "SSH host key shared by several hosts",
+	Level:       RiskMedium,
+	Score:       20,
+	Description: "Identical host keys usually mean machines were cloned from one VM image; one stolen key allows impersonating all of them.",
+	Remediation: "Regenerate the host keys on each machine (ssh-keygen -A after removing /etc/ssh/ssh_host_*).",
+}
+
+// SSHKeyReuse is one host key seen on more than one host
+type SSHKeyReuse struct {
+	SSHHostKey
+	Hosts []ReportFinding `json:"hosts"`
+}
+
+// hostSSHKeys lists the host keys seen on the host's ports
+func hostSSHKeys(h Host) []SSHKeyReuse {
+	var out []SSHKeyReuse
+	for _, p := range h.Ports.Ports {
+		if p.SSH == nil {
+			continue
+		}
+		for _, k := range p.SSH.HostKeys {
+			if k.Fingerprint == "" {
+				continue
+			}
+			out = append(out, SSHKeyReuse{SSHHostKey: k, Hosts: []ReportFinding{{
+				Addr:     h.PrimaryAddr(),
+				Hostname: h.PrimaryName(),
+				Port:     p.PortId,
+				Protocol: p.Protocol,
+			}}})
+		}
+	}
+	return out
+}
+
+// sshKeyReuse merges the per-host keys and keeps those seen on more than
+// one address, most widely shared first
+func sshKeyReuse(keys []SSHKeyReuse) []SSHKeyReuse {
+	index := make(map[string]int)
+	var merged []SSHKeyReuse
+	for _, k := range keys {
+		id := k.Type + " " + k.Fingerprint
+		i, ok := index[id]
+		if !ok {
+			i = len(merged)
+			index[id] = i
+			merged = append(merged, SSHKeyReuse{SSHHostKey: k.SSHHostKey})
+		}
+		merged[i].Hosts = append(merged[i].Hosts, k.Hosts...)
+	}
+
+	var out []SSHKeyReuse
+	for _, m := range merged {
+		addrs := make(map[string]bool)
+		for _, h := range m.Hosts {
+			addrs[h.Addr] = true
+		}
+		if len(addrs) > 1 {
+			out = append(out, m)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return len(out[i].Hosts) > len(out[j].Hosts) })
+	return out
+}
+
+// reuseFindings turns shared keys into report findings, one per location
+func reuseFindings(reuse []SSHKeyReuse) []ReportFinding {
+	var out []ReportFinding
+	for _, r := range reuse {
+		for _, h := range r.Hosts {
+			f := h
+			f.Finding = sshKeyReuseFinding
+			out = append(out, f)
+		}
+	}
+	return out
+}
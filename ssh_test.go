@@ -0,0 +1,104 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzeSSHText(t *testing.T) {
+	p := testPort(22, "ssh")
+	p.Scripts = []Script{
+		{ID: "ssh2-enum-algos", Output: `
+  kex_algorithms: (2)
+      curve25519-sha256
+      diffie-hellman-group1-sha1
+  server_host_key_algorithms: (2)
+      ssh-ed25519
+      ssh-dss
+  encryption_algorithms: (2)
+      aes256-gcm@openssh.com
+      aes128-cbc
+  mac_algorithms: (2)
+      hmac-sha2-256
+      hmac-md5-96
+  compression_algorithms: (1)
+      none`},
+		{ID: "ssh-hostkey", Output: `
+  1024 AA:BB:CC:DD (DSA)
+  256 11:22:33:44 (ED25519)`},
+	}
+	info := analyzeSSH(p)
+	if info == nil {
+		t.Fatal("no SSH info")
+	}
+	if want := []string{"curve25519-sha256", "diffie-hellman-group1-sha1"}; !reflect.DeepEqual(info.Kex, want) {
+		t.Errorf("kex = %v, want %v", info.Kex, want)
+	}
+	if want := []string{"none"}; !reflect.DeepEqual(info.Compression, want) {
+		t.Errorf("compression = %v, want %v", info.Compression, want)
+	}
+	wantKeys := []SSHHostKey{
+		{Type: "ssh-dss", Bits: 1024, Fingerprint: "aabbccdd"},
+		{Type: "ssh-ed25519", Bits: 256, Fingerprint: "11223344"},
+	}
+	if !reflect.DeepEqual(info.HostKeys, wantKeys) {
+		t.Errorf("host keys = %+v, want %+v", info.HostKeys, wantKeys)
+	}
+
+	var weak []string
+	for _, w := range info.Weaknesses {
+		weak = append(weak, w.Category+" "+w.Algorithm)
+	}
+	want := []string{"kex diffie-hellman-group1-sha1", "hostkey ssh-dss", "cipher aes128-cbc", "mac hmac-md5-96", "key ssh-dss"}
+	if !reflect.DeepEqual(weak, want) {
+		t.Errorf("weaknesses = %v, want %v", weak, want)
+	}
+	if !info.WeakAlgorithms() || !info.WeakHostKey() {
+		t.Error("weak algorithms or host key not flagged")
+	}
+}
+
+func TestAnalyzeSSHTables(t *testing.T) {
+	p := testPort(22, "ssh")
+	p.Scripts = []Script{
+		{ID: "ssh2-enum-algos", Tables: []ScriptTable{
+			{Key: "kex_algorithms", Elems: []ScriptElem{{Value: "curve25519-sha256"}}},
+			{Key: "encryption_algorithms", Elems: []ScriptElem{{Value: "chacha20-poly1305@openssh.com"}}},
+		}},
+		{ID: "ssh-hostkey", Tables: []ScriptTable{{Elems: []ScriptElem{
+			{Key: "type", Value: "ssh-rsa"}, {Key: "bits", Value: "3072"}, {Key: "fingerprint", Value: "DE:AD:BE:EF"},
+		}}}},
+	}
+	info := analyzeSSH(p)
+	if info == nil {
+		t.Fatal("no SSH info")
+	}
+	if len(info.Weaknesses) != 0 {
+		t.Errorf("weaknesses = %+v, want none", info.Weaknesses)
+	}
+	if want := []SSHHostKey{{Type: "ssh-rsa", Bits: 3072, Fingerprint: "deadbeef"}}; !reflect.DeepEqual(info.HostKeys, want) {
+		t.Errorf("host keys = %+v, want %+v", info.HostKeys, want)
+	}
+	if analyzeSSH(testPort(80, "http")) != nil {
+		t.Error("SSH info for a port without SSH scripts")
+	}
+}
+
+func TestSSHKeyReuse(t *testing.T) {
+	withKey := func(addr, fp string) Host {
+		p := testPort(22, "ssh")
+		p.SSH = &SSHInfo{HostKeys: []SSHHostKey{{Type: "ssh-ed25519", Bits: 256, Fingerprint: fp}}}
+		return testHost(addr, "up", p)
+	}
+	var keys []SSHKeyReuse
+	for _, h := range []Host{withKey("10.0.0.1", "aa"), withKey("10.0.0.2", "aa"), withKey("10.0.0.3", "bb")} {
+		keys = append(keys, hostSSHKeys(h)...)
+	}
+	reuse := sshKeyReuse(keys)
+	if len(reuse) != 1 || reuse[0].Fingerprint != "aa" || len(reuse[0].Hosts) != 2 {
+		t.Fatalf("reuse = %+v, want key aa on 2 hosts", reuse)
+	}
+	if got := reuseFindings(reuse); len(got) != 2 || got[0].RuleID != "ssh-hostkey-reuse" {
+		t.Errorf("findings = %+v", got)
+	}
+}
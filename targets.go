@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// targetCategories are the report's filter chip categories, each written to
+// its own <name>.txt list
+var targetCategories = []struct {
+	Name  string
+	Match func(service string) bool
+}{
+	{"web", func(s string) bool { return serviceMatches(s, webServices) }},
+	{"database", func(s string) bool { return serviceMatches(s, databaseServices) }},
+	{"ssh", func(s string) bool { return serviceMatches(s, []string{"ssh"}) }},
+	{"windows", func(s string) bool { return serviceMatches(s, windowsServices) }},
+	{"critical", func(s string) bool { return serviceMatches(s, criticalServices) }},
+}
+
+// hostPort joins an address and port, bracketing IPv6 literals
+func hostPort(addr string, port int) string {
+	return net.JoinHostPort(addr, strconv.Itoa(port))
+}
+
+// portURL derives a web URL for the port, or "" if it does not look like HTTP.
+// TLS is taken from the ssl tunnel or an https service name.
+func portURL(addr string, p Port) string {
+	name := strings.ToLower(p.Service.Name)
+	if !serviceMatches(name, webServices) {
+		return ""
+	}
+	scheme := "http"
+	if p.Service.Tunnel == "ssl" || strings.HasPrefix(name, "https") || strings.HasPrefix(name, "ssl/") {
+		scheme = "https"
+	}
+	return scheme + "://" + hostPort(addr, p.PortId)
+}
+
+// target is one list entry, kept with its address and port for sorting
+type target struct {
+	addr string
+	port int
+	line string
+}
+
+// compareAddrs orders addresses numerically, IPv4 before IPv6, with
+// anything that is not an IP address last
+func compareAddrs(a, b string) int {
+	ipA, errA := netip.ParseAddr(a)
+	ipB, errB := netip.ParseAddr(b)
+	switch {
+	case errA != nil && errB != nil:
+		return strings.Compare(a, b)
+	case errA != nil:
+		return 1
+	case errB != nil:
+		return -1
+	}
+	return ipA.Unmap().Compare(ipB.Unmap())
+}
+
+// buildTargets collects the target lists keyed by file name. Tools reading
+// ip:port lists expect TCP, so UDP ports only go to ip-port-udp.txt.
+func buildTargets(hosts []Host) map[string][]string {
+	lists := map[string][]target{
+		"ip-port.txt":     nil,
+		"ip-port-udp.txt": nil,
+		"urls.txt":        nil,
+	}
+	for _, c := range targetCategories {
+		lists[c.Name+".txt"] = nil
+	}
+
+	for _, h := range hosts {
+		addr := h.PrimaryAddr()
+		if addr == "" {
+			continue
+		}
+		for _, p := range h.OpenPorts() {
+			t := target{addr: addr, port: p.PortId, line: hostPort(addr, p.PortId)}
+			if p.Protocol == "udp" {
+				lists["ip-port-udp.txt"] = append(lists["ip-port-udp.txt"], t)
+				continue
+			}
+			lists["ip-port.txt"] = append(lists["ip-port.txt"], t)
+			if u := portURL(addr, p); u != "" {
+				lists["urls.txt"] = append(lists["urls.txt"], target{addr: addr, port: p.PortId, line: u})
+			}
+			for _, c := range targetCategories {
+				if c.Match(p.Service.Name) {
+					lists[c.Name+".txt"] = append(lists[c.Name+".txt"], t)
+				}
+			}
+		}
+	}
+
+	out := make(map[string][]string, len(lists))
+	for name, list := range lists {
+		out[name] = sortTargets(list)
+	}
+	return out
+}
+
+// sortTargets orders targets by address, then port, and drops duplicates
+func sortTargets(list []target) []string {
+	sort.SliceStable(list, func(i, j int) bool {
+		if c := compareAddrs(list[i].addr, list[j].addr); c != 0 {
+			return c < 0
+		}
+		if list[i].port != list[j].port {
+			return list[i].port < list[j].port
+		}
+		return list[i].line < list[j].line
+	})
+	seen := make(map[string]bool, len(list))
+	var out []string
+	for _, t := range list {
+		if !seen[t.line] {
+			seen[t.line] = true
+			out = append(out, t.line)
+		}
+	}
+	return out
+}
+
+// writeTargets writes one list per file into dir, creating it if needed.
+// Empty categories still get an (empty) file so scripts can rely on them.
+func writeTargets(dir string, hosts []Host) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create target directory: %w", err)
+	}
+	for name, list := range buildTargets(hosts) {
+		content := strings.Join(list, "\n")
+		if content != "" {
+			content += "\n"
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareAddrs(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"10.0.0.9", "10.0.0.10", -1},
+		{"10.0.0.10", "10.0.0.9", 1},
+		{"192.168.1.1", "192.168.1.1", 0},
+		{"10.0.0.1", "::1", -1},
+		{"2001:db8::2", "2001:db8::10", -1},
+		{"00:11:22:33:44:55", "10.0.0.1", 1},
+	}
+	for _, tt := range tests {
+		if got := compareAddrs(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareAddrs(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestBuildTargets(t *testing.T) {
+	dns := testPort(53, "domain")
+	dns.Protocol = "udp"
+	hosts := []Host{
+		testHost("10.0.0.10", "up", testPort(80, "http")),
+		testHost("10.0.0.9", "up", testPort(443, "https"), testPort(22, "ssh"), dns),
+	}
+	lists := buildTargets(hosts)
+
+	tests := []struct {
+		file string
+		want []string
+	}{
+		{"ip-port.txt", []string{"10.0.0.9:22", "10.0.0.9:443", "10.0.0.10:80"}},
+		{"ip-port-udp.txt", []string{"10.0.0.9:53"}},
+		{"urls.txt", []string{"https://10.0.0.9:443", "http://10.0.0.10:80"}},
+		{"ssh.txt", []string{"10.0.0.9:22"}},
+	}
+	for _, tt := range tests {
+		if got := lists[tt.file]; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %q, want %q", tt.file, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,436 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TLSInfo is the Go-side analysis of ssl-enum-ciphers and ssl-cert output
+type TLSInfo struct {
+	Protocols     []TLSProtocol `json:"protocols,omitempty"`
+	LeastStrength string        `json:"least_strength,omitempty"`
+	Cert          *Certificate  `json:"certificate,omitempty"`
+}
+
+// TLSProtocol is one protocol version offered by the server
+type TLSProtocol struct {
+	Name     string      `json:"name"`
+	Ciphers  []TLSCipher `json:"ciphers,omitempty"`
+	Warnings []string    `json:"warnings,omitempty"`
+}
+
+type TLSCipher struct {
+	Name     string `json:"name"`
+	Strength string `json:"strength,omitempty"` // nmap grade, A (best) to F
+}
+
+// Deprecated reports SSLv2/SSLv3/TLS 1.0/TLS 1.1
+func (p TLSProtocol) Deprecated() bool {
+	switch p.Name {
+	case "SSLv2", "SSLv3", "TLSv1.0", "TLSv1.1":
+		return true
+	}
+	return false
+}
+
+// weakCipherMarkers flag ciphers that are broken regardless of grade
+var weakCipherMarkers = []string{"_NULL_", "_EXPORT", "_anon_", "_RC4_", "_DES_", "3DES", "_MD5"}
+
+// Weak reports ciphers graded C or worse, or using a broken primitive
+func (c TLSCipher) Weak() bool {
+	if len(c.Strength) == 1 && c.Strength >= "C" {
+		return true
+	}
+	for _, m := range weakCipherMarkers {
+		if strings.Contains(c.Name, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// DeprecatedProtocols lists the offered protocol versions that should be disabled
+func (t TLSInfo) DeprecatedProtocols() []string {
+	var out []string
+	for _, p := range t.Protocols {
+		if p.Deprecated() {
+			out = append(out, p.Name)
+		}
+	}
+	return out
+}
+
+// WeakCiphers lists the distinct weak ciphers across all protocol versions
+func (t TLSInfo) WeakCiphers() []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, p := range t.Protocols {
+		for _, c := range p.Ciphers {
+			if c.Weak() && !seen[c.Name] {
+				seen[c.Name] = true
+				out = append(out, c.Name)
+			}
+		}
+	}
+	return out
+}
+
+// Certificate is the leaf certificate reported by ssl-cert
+type Certificate struct {
+	Subject    string    `json:"subject"` // commonName, or the full DN without one
+	SubjectDN  string    `json:"subject_dn,omitempty"`
+	SANs       []string  `json:"sans,omitempty"`
+	Issuer     string    `json:"issuer"`
+	IssuerDN   string    `json:"issuer_dn,omitempty"`
+	KeyType    string    `json:"key_type,omitempty"`
+	KeyBits    int       `json:"key_bits,omitempty"`
+	SigAlgo    string    `json:"signature_algorithm,omitempty"`
+	NotBefore  time.Time `json:"not_before,omitempty"`
+	NotAfter   time.Time `json:"not_after,omitempty"`
+	SelfSigned bool      `json:"self_signed"`
+	Days       int       `json:"days_to_expiry"` // negative once expired, set when the port is enriched
+}
+
+// Expired reports whether the certificate was past its validity at analysis time
+func (c Certificate) Expired() bool {
+	return !c.NotAfter.IsZero() && c.Days < 0
+}
+
+// Expiry is the status shown next to the expiry date
+func (c Certificate) Expiry() string {
+	switch {
+	case c.NotAfter.IsZero():
+		return "unknown"
+	case c.Days < 0:
+		return "expired " + strconv.Itoa(-c.Days) + " days ago"
+	case c.Days == 0:
+		return "expires today"
+	default:
+		return "expires in " + strconv.Itoa(c.Days) + " days"
+	}
+}
+
+// ExpiryLevel colours the expiry in the report
+func (c Certificate) ExpiryLevel() string {
+	switch {
+	case c.NotAfter.IsZero():
+		return RiskInfo
+	case c.Days < 0:
+		return RiskHigh
+	case c.Days <= 30:
+		return RiskMedium
+	default:
+		return RiskLow
+	}
+}
+
+// WeakKey reports RSA/DSA keys under 2048 bits and EC keys under 224 bits
+func (c Certificate) WeakKey() bool {
+	if c.KeyBits == 0 {
+		return false
+	}
+	if strings.HasPrefix(strings.ToLower(c.KeyType), "ec") {
+		return c.KeyBits < 224
+	}
+	return c.KeyBits < 2048
+}
+
+// WeakSignature reports MD5 or SHA-1 based signatures
+func (c Certificate) WeakSignature() bool {
+	s := strings.ToLower(c.SigAlgo)
+	return strings.Contains(s, "md5") || strings.Contains(s, "sha1")
+}
+
+// analyzeTLS builds the TLS summary for a port, nil when neither script ran
+func analyzeTLS(p Port, now time.Time) *TLSInfo {
+	var info TLSInfo
+	found := false
+	for _, s := range p.Scripts {
+		switch s.ID {
+		case "ssl-enum-ciphers":
+			found = true
+			if len(s.Tables) > 0 {
+				parseEnumCiphersTables(s, &info)
+			} else {
+				parseEnumCiphersText(s.Output, &info)
+			}
+		case "ssl-cert":
+			found = true
+			var c *Certificate
+			if len(s.Tables) > 0 || len(s.Elems) > 0 {
+				c = parseCertTables(s)
+			} else {
+				c = parseCertText(s.Output)
+			}
+			if !c.NotAfter.IsZero() {
+				c.Days = int(c.NotAfter.Sub(now).Hours() / 24)
+				if c.NotAfter.Before(now) && c.Days == 0 {
+					c.Days = -1
+				}
+			}
+			info.Cert = c
+		}
+	}
+	if !found {
+		return nil
+	}
+	return &info
+}
+
+func (t ScriptTable) elem(key string) string {
+	for _, e := range t.Elems {
+		if e.Key == key {
+			return strings.TrimSpace(e.Value)
+		}
+	}
+	return ""
+}
+
+func (t ScriptTable) table(key string) (ScriptTable, bool) {
+	for _, sub := range t.Tables {
+		if sub.Key == key {
+			return sub, true
+		}
+	}
+	return ScriptTable{}, false
+}
+
+// root gives the script's top-level table/elem children the ScriptTable helpers
+func (s Script) root() ScriptTable {
+	return ScriptTable{Tables: s.Tables, Elems: s.Elems}
+}
+
+func parseEnumCiphersTables(s Script, info *TLSInfo) {
+	root := s.root()
+	info.LeastStrength = root.elem("least strength")
+	for _, t := range root.Tables {
+		proto := TLSProtocol{Name: t.Key}
+		if ciphers, ok := t.table("ciphers"); ok {
+			for _, c := range ciphers.Tables {
+				proto.Ciphers = append(proto.Ciphers, TLSCipher{Name: c.elem("name"), Strength: c.elem("strength")})
+			}
+		}
+		if warnings, ok := t.table("warnings"); ok {
+			for _, w := range warnings.Elems {
+				proto.Warnings = append(proto.Warnings, strings.TrimSpace(w.Value))
+			}
+		}
+		info.Protocols = append(info.Protocols, proto)
+	}
+	sortProtocols(info.Protocols)
+}
+
+// trimScriptLine strips the "|" and "|_" prefixes of normal-format output
+func trimScriptLine(line string) string {
+	return strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "|_"))
+}
+
+// parseEnumCiphersText reads the indented text layout:
+//
+//	TLSv1.2:
+//	  ciphers:
+//	    TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 (secp256r1) - A
+//	  warnings:
+//	    64-bit block cipher 3DES vulnerable to SWEET32 attack
+//	least strength: A
+func parseEnumCiphersText(output string, info *TLSInfo) {
+	var cur *TLSProtocol
+	section := ""
+	for _, raw := range strings.Split(output, "\n") {
+		line := trimScriptLine(raw)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "least strength:"):
+			info.LeastStrength = strings.TrimSpace(strings.TrimPrefix(line, "least strength:"))
+		case strings.HasSuffix(line, ":") && (strings.HasPrefix(line, "SSLv") || strings.HasPrefix(line, "TLSv")):
+			info.Protocols = append(info.Protocols, TLSProtocol{Name: strings.TrimSuffix(line, ":")})
+			cur = &info.Protocols[len(info.Protocols)-1]
+			section = ""
+		case strings.HasSuffix(line, ":"):
+			section = strings.TrimSuffix(line, ":")
+		case cur == nil || strings.Contains(line, ": "):
+			// "cipher preference: server" and similar
+		case section == "ciphers":
+			c := TLSCipher{Name: strings.Fields(line)[0]}
+			if i := strings.LastIndex(line, " - "); i >= 0 {
+				c.Strength = strings.TrimSpace(line[i+3:])
+			}
+			cur.Ciphers = append(cur.Ciphers, c)
+		case section == "warnings":
+			cur.Warnings = append(cur.Warnings, line)
+		}
+	}
+	sortProtocols(info.Protocols)
+}
+
+// sortProtocols orders oldest protocol first (SSLv3 before TLSv1.0 ...)
+func sortProtocols(protocols []TLSProtocol) {
+	sort.SliceStable(protocols, func(i, j int) bool {
+		a, b := protocols[i].Name, protocols[j].Name
+		if strings.HasPrefix(a, "SSL") != strings.HasPrefix(b, "SSL") {
+			return strings.HasPrefix(a, "SSL")
+		}
+		return compareVersions(a, b) < 0
+	})
+}
+
+// certTimeLayouts covers the nmap formats with and without a zone offset
+var certTimeLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04:05-07:00", "2006-01-02T15:04:05Z07:00", "2006-01-02"}
+
+func parseCertTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range certTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// distinguishedName renders a subject/issuer table as "commonName=x/organizationName=y"
+// and returns the commonName separately
+func distinguishedName(t ScriptTable) (cn, dn string) {
+	var parts []string
+	for _, e := range t.Elems {
+		parts = append(parts, e.Key+"="+strings.TrimSpace(e.Value))
+		if e.Key == "commonName" {
+			cn = strings.TrimSpace(e.Value)
+		}
+	}
+	return cn, strings.Join(parts, "/")
+}
+
+func parseCertTables(s Script) *Certificate {
+	root := s.root()
+	c := &Certificate{SigAlgo: root.elem("sig_algo")}
+	if t, ok := root.table("subject"); ok {
+		c.Subject, c.SubjectDN = distinguishedName(t)
+	}
+	if t, ok := root.table("issuer"); ok {
+		c.Issuer, c.IssuerDN = distinguishedName(t)
+	}
+	if t, ok := root.table("pubkey"); ok {
+		c.KeyType = t.elem("type")
+		c.KeyBits, _ = strconv.Atoi(t.elem("bits"))
+	}
+	if t, ok := root.table("validity"); ok {
+		c.NotBefore = parseCertTime(t.elem("notBefore"))
+		c.NotAfter = parseCertTime(t.elem("notAfter"))
+	}
+	if t, ok := root.table("extensions"); ok {
+		for _, ext := range t.Tables {
+			if ext.elem("name") == "X509v3 Subject Alternative Name" {
+				c.SANs = splitSANs(ext.elem("value"))
+			}
+		}
+	}
+	finishCert(c)
+	return c
+}
+
+// parseCertText reads the "Key: value" lines of the text output
+func parseCertText(output string) *Certificate {
+	c := &Certificate{}
+	for _, raw := range strings.Split(output, "\n") {
+		line := trimScriptLine(raw)
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "Subject":
+			c.SubjectDN = value
+			c.Subject = dnField(value, "commonName")
+		case "Issuer":
+			c.IssuerDN = value
+			c.Issuer = dnField(value, "commonName")
+		case "Subject Alternative Name":
+			c.SANs = splitSANs(value)
+		case "Public Key type":
+			c.KeyType = value
+		case "Public Key bits":
+			c.KeyBits, _ = strconv.Atoi(value)
+		case "Signature Algorithm":
+			c.SigAlgo = value
+		case "Not valid before":
+			c.NotBefore = parseCertTime(value)
+		case "Not valid after":
+			c.NotAfter = parseCertTime(value)
+		}
+	}
+	finishCert(c)
+	return c
+}
+
+// dnField picks one attribute out of "commonName=x/organizationName=y"
+func dnField(dn, key string) string {
+	for _, part := range strings.Split(dn, "/") {
+		if k, v, ok := strings.Cut(part, "="); ok && k == key {
+			return v
+		}
+	}
+	return ""
+}
+
+// splitSANs turns "DNS:a.example, DNS:b.example, IP Address:10.0.0.1" into entries
+func splitSANs(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if _, v, ok := strings.Cut(part, ":"); ok {
+			part = strings.TrimSpace(v)
+		}
+		if part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func finishCert(c *Certificate) {
+	c.SelfSigned = c.SubjectDN != "" && c.SubjectDN == c.IssuerDN
+	if c.Subject == "" {
+		c.Subject = c.SubjectDN
+	}
+	if c.Issuer == "" {
+		c.Issuer = c.IssuerDN
+	}
+}
+
+// CertEntry places a certificate on its host and port for the inventory
+type CertEntry struct {
+	Certificate
+	Location ReportFinding `json:"location"`
+}
+
+// hostCertificates lists the certificates seen on the host's ports
+func hostCertificates(h Host) []CertEntry {
+	var out []CertEntry
+	for _, p := range h.Ports.Ports {
+		if p.TLS == nil || p.TLS.Cert == nil {
+			continue
+		}
+		out = append(out, CertEntry{Certificate: *p.TLS.Cert, Location: ReportFinding{
+			Addr:     h.PrimaryAddr(),
+			Hostname: h.PrimaryName(),
+			Port:     p.PortId,
+			Protocol: p.Protocol,
+		}})
+	}
+	return out
+}
+
+// sortCertificates orders by expiry, soonest (or longest expired) first;
+// certificates without a parsed expiry go last
+func sortCertificates(certs []CertEntry) {
+	sort.SliceStable(certs, func(i, j int) bool {
+		a, b := certs[i].NotAfter, certs[j].NotAfter
+		if a.IsZero() != b.IsZero() {
+			return b.IsZero()
+		}
+		return a.Before(b)
+	})
+}
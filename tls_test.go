@@ -0,0 +1,114 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseEnumCiphersText(t *testing.T) {
+	output := `
+  TLSv1.2:
+    ciphers:
+      TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 (secp256r1) - A
+      TLS_RSA_WITH_3DES_EDE_CBC_SHA (rsa 2048) - C
+    compressors:
+      NULL
+    cipher preference: server
+    warnings:
+      64-bit block cipher 3DES vulnerable to SWEET32 attack
+  TLSv1.0:
+    ciphers:
+      TLS_RSA_WITH_RC4_128_SHA (rsa 2048) - A
+  least strength: C`
+	var info TLSInfo
+	parseEnumCiphersText(output, &info)
+
+	if info.LeastStrength != "C" {
+		t.Errorf("least strength = %q, want C", info.LeastStrength)
+	}
+	var names []string
+	for _, p := range info.Protocols {
+		names = append(names, p.Name)
+	}
+	if want := []string{"TLSv1.0", "TLSv1.2"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("protocols = %v, want %v", names, want)
+	}
+	tls12 := info.Protocols[1]
+	if len(tls12.Ciphers) != 2 || tls12.Ciphers[1].Strength != "C" {
+		t.Errorf("TLSv1.2 ciphers = %+v", tls12.Ciphers)
+	}
+	if len(tls12.Warnings) != 1 {
+		t.Errorf("TLSv1.2 warnings = %v", tls12.Warnings)
+	}
+	if got, want := info.DeprecatedProtocols(), []string{"TLSv1.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("deprecated = %v, want %v", got, want)
+	}
+	want := []string{"TLS_RSA_WITH_RC4_128_SHA", "TLS_RSA_WITH_3DES_EDE_CBC_SHA"}
+	if got := info.WeakCiphers(); !reflect.DeepEqual(got, want) {
+		t.Errorf("weak ciphers = %v, want %v", got, want)
+	}
+}
+
+func TestParseCert(t *testing.T) {
+	text := `Subject: commonName=www.example.com/organizationName=Example
+Subject Alternative Name: DNS:www.example.com, DNS:example.com, IP Address:10.0.0.1
+Issuer: commonName=www.example.com/organizationName=Example
+Public Key type: rsa
+Public Key bits: 1024
+Signature Algorithm: sha1WithRSAEncryption
+Not valid before: 2024-01-01T00:00:00
+Not valid after:  2025-01-01T00:00:00`
+	tables := Script{ID: "ssl-cert", Tables: []ScriptTable{
+		{Key: "subject", Elems: []ScriptElem{{Key: "commonName", Value: "www.example.com"}, {Key: "organizationName", Value: "Example"}}},
+		{Key: "issuer", Elems: []ScriptElem{{Key: "commonName", Value: "www.example.com"}, {Key: "organizationName", Value: "Example"}}},
+		{Key: "pubkey", Elems: []ScriptElem{{Key: "type", Value: "rsa"}, {Key: "bits", Value: "1024"}}},
+		{Key: "extensions", Tables: []ScriptTable{{Elems: []ScriptElem{
+			{Key: "name", Value: "X509v3 Subject Alternative Name"},
+			{Key: "value", Value: "DNS:www.example.com, DNS:example.com, IP Address:10.0.0.1"},
+		}}}},
+		{Key: "validity", Elems: []ScriptElem{{Key: "notBefore", Value: "2024-01-01T00:00:00"}, {Key: "notAfter", Value: "2025-01-01T00:00:00"}}},
+	}, Elems: []ScriptElem{{Key: "sig_algo", Value: "sha1WithRSAEncryption"}}}
+
+	want := &Certificate{
+		Subject:    "www.example.com",
+		SubjectDN:  "commonName=www.example.com/organizationName=Example",
+		SANs:       []string{"www.example.com", "example.com", "10.0.0.1"},
+		Issuer:     "www.example.com",
+		IssuerDN:   "commonName=www.example.com/organizationName=Example",
+		KeyType:    "rsa",
+		KeyBits:    1024,
+		SigAlgo:    "sha1WithRSAEncryption",
+		NotBefore:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		SelfSigned: true,
+	}
+	for name, got := range map[string]*Certificate{"text": parseCertText(text), "tables": parseCertTables(tables)} {
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, want %+v", name, got, want)
+		}
+		if !got.WeakKey() || !got.WeakSignature() {
+			t.Errorf("%s: weak key/signature not flagged", name)
+		}
+	}
+}
+
+func TestAnalyzeTLSExpiry(t *testing.T) {
+	p := testPort(443, "https")
+	p.Scripts = []Script{{ID: "ssl-cert", Output: "Subject: commonName=a\nNot valid after: 2026-01-11T00:00:00"}}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	info := analyzeTLS(p, now)
+	if info == nil || info.Cert == nil {
+		t.Fatal("no certificate")
+	}
+	if info.Cert.Days != 10 || info.Cert.Expired() || info.Cert.ExpiryLevel() != RiskMedium {
+		t.Errorf("days = %d, expired = %v, level = %s", info.Cert.Days, info.Cert.Expired(), info.Cert.ExpiryLevel())
+	}
+	if info := analyzeTLS(p, now.AddDate(0, 1, 0)); !info.Cert.Expired() {
+		t.Errorf("certificate not expired a month later (days = %d)", info.Cert.Days)
+	}
+	if analyzeTLS(testPort(80, "http"), now) != nil {
+		t.Error("TLS info for a port without TLS scripts")
+	}
+}
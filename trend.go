@@ -0,0 +1,469 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TrendPoint summarises one scan in the series
+type TrendPoint struct {
+	Source    string         `json:"source"`
+	Started   time.Time      `json:"started"`
+	Label     string         `json:"label"` // short x-axis label, unique in the series
+	HostsUp   int            `json:"hosts_up"`
+	OpenPorts int            `json:"open_ports"`
+	Services  map[string]int `json:"services"`
+}
+
+// labelPoints names each scan by its date, adding the time when two scans
+// share a day and the series position when that is still ambiguous
+func labelPoints(points []TrendPoint) {
+	for _, layout := range []string{"Jan 2", "Jan 2 15:04"} {
+		seen := make(map[string]bool)
+		unique := true
+		for i := range points {
+			p := &points[i]
+			p.Label = filepath.Base(p.Source)
+			if !p.Started.IsZero() {
+				p.Label = p.Started.Format(layout)
+			}
+			unique = unique && !seen[p.Label]
+			seen[p.Label] = true
+		}
+		if unique {
+			return
+		}
+	}
+	for i := range points {
+		points[i].Label = fmt.Sprintf("%s #%d", points[i].Label, i+1)
+	}
+}
+
+// FlappingPort is a host:port whose open state changed more than once
+type FlappingPort struct {
+	Addr     string `json:"addr"`
+	Hostname string `json:"hostname,omitempty"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	Service  string `json:"service,omitempty"`
+	Open     []bool `json:"open"`    // one entry per scan, in series order
+	HostUp   []bool `json:"host_up"` // scans where the host was down say nothing about the port
+	Changes  int    `json:"changes"`
+}
+
+// States is "open", "closed" or "down" per scan, for the table cells
+func (f FlappingPort) States() []string {
+	out := make([]string, len(f.Open))
+	for i, open := range f.Open {
+		switch {
+		case !f.HostUp[i]:
+			out[i] = "down"
+		case open:
+			out[i] = "open"
+		default:
+			out[i] = "closed"
+		}
+	}
+	return out
+}
+
+// TrendReport is the model behind the trend HTML and JSON outputs
+type TrendReport struct {
+	Points   []TrendPoint   `json:"scans"`
+	Services []string       `json:"top_services"`
+	Flapping []FlappingPort `json:"flapping"`
+}
+
+// maxTrendServices caps how many services get their own chart line
+const maxTrendServices = 8
+
+// expandSeries turns a comma separated list of files and globs into paths
+func expandSeries(spec string) ([]string, error) {
+	var paths []string
+	for _, item := range splitList(spec) {
+		matches, err := filepath.Glob(item)
+		if err != nil {
+			return nil, fmt.Errorf("bad pattern %q: %w", item, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %q", item)
+		}
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
+
+type seriesScan struct {
+	source string
+	info   NmapRunInfo
+	hosts  []Host
+}
+
+// buildTrend orders the scans by their nmaprun start time and computes the
+// per-scan counts and the flapping ports
+func buildTrend(scans []seriesScan) TrendReport {
+	sort.SliceStable(scans, func(i, j int) bool {
+		return scans[i].info.Started().Before(scans[j].info.Started())
+	})
+
+	report := TrendReport{Flapping: []FlappingPort{}}
+	type portHistory struct {
+		port FlappingPort
+		open []bool
+	}
+	history := make(map[string]*portHistory)
+	var order []string
+	hostUp := make(map[string][]bool) // per address, one entry per scan
+	peak := make(map[string]int)
+
+	for i, scan := range scans {
+		point := TrendPoint{Source: scan.source, Started: scan.info.Started(), Services: make(map[string]int)}
+		for _, h := range scan.hosts {
+			if h.Status.State != "up" {
+				continue
+			}
+			point.HostsUp++
+			if hostUp[h.PrimaryAddr()] == nil {
+				hostUp[h.PrimaryAddr()] = make([]bool, len(scans))
+			}
+			hostUp[h.PrimaryAddr()][i] = true
+			for _, p := range h.OpenPorts() {
+				point.OpenPorts++
+				if p.Service.Name != "" {
+					point.Services[p.Service.Name]++
+				}
+				key := h.PrimaryAddr() + " " + portKey(p)
+				ph, ok := history[key]
+				if !ok {
+					ph = &portHistory{
+						port: FlappingPort{Addr: h.PrimaryAddr(), Hostname: h.PrimaryName(), Port: p.PortId, Protocol: p.Protocol},
+						open: make([]bool, len(scans)),
+					}
+					history[key] = ph
+					order = append(order, key)
+				}
+				ph.open[i] = true
+				if p.Service.Name != "" {
+					ph.port.Service = p.Service.Name
+				}
+			}
+		}
+		for svc, n := range point.Services {
+			if n > peak[svc] {
+				peak[svc] = n
+			}
+		}
+		report.Points = append(report.Points, point)
+	}
+	labelPoints(report.Points)
+
+	for _, key := range order {
+		ph := history[key]
+		up := hostUp[ph.port.Addr]
+		// compare only scans where the host was up
+		changes, last := 0, -1
+		for i := range ph.open {
+			if !up[i] {
+				continue
+			}
+			if last >= 0 && ph.open[i] != ph.open[last] {
+				changes++
+			}
+			last = i
+		}
+		// one change is a plain open or close; flapping needs it to come back
+		if changes >= 2 {
+			ph.port.Open = ph.open
+			ph.port.HostUp = up
+			ph.port.Changes = changes
+			report.Flapping = append(report.Flapping, ph.port)
+		}
+	}
+	sort.SliceStable(report.Flapping, func(i, j int) bool {
+		return report.Flapping[i].Changes > report.Flapping[j].Changes
+	})
+
+	for svc := range peak {
+		report.Services = append(report.Services, svc)
+	}
+	sort.Slice(report.Services, func(i, j int) bool {
+		a, b := report.Services[i], report.Services[j]
+		if peak[a] != peak[b] {
+			return peak[a] > peak[b]
+		}
+		return a < b
+	})
+	if len(report.Services) > maxTrendServices {
+		report.Services = report.Services[:maxTrendServices]
+	}
+	return report
+}
+
+// chartSeries is one line on an SVG chart
+type chartSeries struct {
+	Name   string
+	Values []int
+}
+
+// chartPalette follows the report's accent, success, warning and danger colours
+var chartPalette = []string{"#38bdf8", "#10b981", "#f59e0b", "#fb7185", "#a78bfa", "#60a5fa", "#f472b6", "#facc15"}
+
+// lineChartSVG renders a self-contained inline SVG line chart
+func lineChartSVG(labels []string, series []chartSeries) template.HTML {
+	const (
+		width, height = 720.0, 240.0
+		left, right   = 44.0, 16.0
+		top, bottom   = 16.0, 36.0
+	)
+	maxVal := 1
+	for _, s := range series {
+		for _, v := range s.Values {
+			if v > maxVal {
+				maxVal = v
+			}
+		}
+	}
+	plotW, plotH := width-left-right, height-top-bottom
+	x := func(i int) float64 {
+		if len(labels) <= 1 {
+			return left + plotW/2
+		}
+		return left + plotW*float64(i)/float64(len(labels)-1)
+	}
+	y := func(v int) float64 { return top + plotH - plotH*float64(v)/float64(maxVal) }
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="trend-chart" viewBox="0 0 %.0f %.0f" width="100%%" role="img" xmlns="http://www.w3.org/2000/svg">`, width, height)
+	// horizontal grid lines with value labels
+	for _, v := range []int{0, maxVal / 2, maxVal} {
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="rgba(255,255,255,0.08)"/>`, left, y(v), width-right, y(v))
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" fill="#9aa4b2" font-size="11" text-anchor="end">%d</text>`, left-6, y(v)+4, v)
+	}
+	for i, l := range labels {
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" fill="#9aa4b2" font-size="11" text-anchor="middle">%s</text>`, x(i), height-12, template.HTMLEscapeString(l))
+	}
+	for si, s := range series {
+		color := chartPalette[si%len(chartPalette)]
+		var pts []string
+		for i, v := range s.Values {
+			pts = append(pts, fmt.Sprintf("%.1f,%.1f", x(i), y(v)))
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`, color, strings.Join(pts, " "))
+		for i, v := range s.Values {
+			fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s"><title>%s: %d</title></circle>`, x(i), y(v), color, template.HTMLEscapeString(s.Name+" @ "+labels[i]), v)
+		}
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// chartLegend renders the colour key for a multi-series chart
+func chartLegend(series []chartSeries) template.HTML {
+	var b strings.Builder
+	b.WriteString(`<div class="chart-legend">`)
+	for i, s := range series {
+		fmt.Fprintf(&b, `<span><i style="background:%s"></i>%s</span>`, chartPalette[i%len(chartPalette)], template.HTMLEscapeString(s.Name))
+	}
+	b.WriteString(`</div>`)
+	return template.HTML(b.String())
+}
+
+// TrendTemplateData is the context for the embedded trend template
+type TrendTemplateData struct {
+	Trend         TrendReport
+	CSS           template.CSS
+	Generated     time.Time
+	Labels        []string
+	PortsChart    template.HTML
+	HostsChart    template.HTML
+	ServiceChart  template.HTML
+	ServiceLegend template.HTML
+}
+
+func newTrendTemplateData(t TrendReport, css string) TrendTemplateData {
+	labels := make([]string, len(t.Points))
+	ports := chartSeries{Name: "Open ports"}
+	hosts := chartSeries{Name: "Hosts up"}
+	for i, p := range t.Points {
+		labels[i] = p.Label
+		ports.Values = append(ports.Values, p.OpenPorts)
+		hosts.Values = append(hosts.Values, p.HostsUp)
+	}
+	var services []chartSeries
+	for _, svc := range t.Services {
+		s := chartSeries{Name: svc}
+		for _, p := range t.Points {
+			s.Values = append(s.Values, p.Services[svc])
+		}
+		services = append(services, s)
+	}
+	return TrendTemplateData{
+		Trend:         t,
+		CSS:           template.CSS(css),
+		Generated:     time.Now(),
+		Labels:        labels,
+		PortsChart:    lineChartSVG(labels, []chartSeries{ports}),
+		HostsChart:    lineChartSVG(labels, []chartSeries{hosts}),
+		ServiceChart:  lineChartSVG(labels, services),
+		ServiceLegend: chartLegend(services),
+	}
+}
+
+// runTrend loads every scan in the series and writes the trend report
+func runTrend(spec, outPath, format, css string) error {
+	if format != "html" && format != "json" {
+		return fmt.Errorf("trend mode supports -format html or json, not %q", format)
+	}
+	paths, err := expandSeries(spec)
+	if err != nil {
+		return err
+	}
+	if len(paths) < 2 {
+		return fmt.Errorf("a trend needs at least two scans, got %d", len(paths))
+	}
+
+	var scans []seriesScan
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		info, hosts, err := loadScan(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		scans = append(scans, seriesScan{source: path, info: info, hosts: hosts})
+	}
+	trend := buildTrend(scans)
+
+	out, err := createOutput(outPath)
+	if err != nil {
+		return fmt.Errorf("create output: %w", err)
+	}
+	defer out.Close()
+
+	if format == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(trend)
+	} else {
+		tpl, perr := template.New("trend").Parse(trendTemplate)
+		if perr != nil {
+			return fmt.Errorf("parse trend template: %w", perr)
+		}
+		err = tpl.Execute(out, newTrendTemplateData(trend, css))
+	}
+	if err != nil {
+		return fmt.Errorf("write trend: %w", err)
+	}
+	return out.Close()
+}
+
+// Embedded trend template, styled by the same CSS as the main report
+const trendTemplate = `<!doctype html>
+<html lang="en">
+<head>
+  <meta charset="utf-8"/>
+  <meta name="viewport" content="width=device-width,initial-scale=1"/>
+  <title>Nmap Trend Report</title>
+  <style>{{.CSS}}
+  .trend-card{background:var(--glass);border:1px solid var(--border);border-radius:14px;padding:16px 20px;margin:20px 0}
+  .trend-card h2{margin:0 0 12px 0;font-size:16px}
+  .chart-legend{display:flex;flex-wrap:wrap;gap:12px;margin-top:8px;font-size:12px;color:var(--muted)}
+  .chart-legend i{display:inline-block;width:10px;height:10px;border-radius:2px;margin-right:6px}
+  .flap-open{color:var(--success)}
+  .flap-closed{color:var(--muted)}
+  </style>
+</head>
+<body>
+  <header class="topbar">
+    <div class="container">
+      <div class="brand">
+        <svg class="logo" viewBox="0 0 24 24" aria-hidden="true">
+          <path d="M12 2L2 7l10 5 10-5-10-5zM2 17l10 5 10-5M2 12l10 5 10-5" stroke="currentColor" stroke-width="1.5" stroke-linecap="round" stroke-linejoin="round" fill="none"/>
+        </svg>
+        <div>
+          <h1>Trend Report</h1>
+          <p class="muted">{{len .Trend.Points}} scans • Generated: {{.Generated.Format "Jan 2, 2006 15:04"}}</p>
+        </div>
+      </div>
+    </div>
+  </header>
+
+  <main class="container">
+    <section class="trend-card">
+      <h2>🔓 Open Ports Over Time</h2>
+      {{.PortsChart}}
+    </section>
+
+    <section class="trend-card">
+      <h2>🖥️ Hosts Up</h2>
+      {{.HostsChart}}
+    </section>
+
+    {{if .Trend.Services}}
+    <section class="trend-card">
+      <h2>🛡️ Services</h2>
+      {{.ServiceChart}}
+      {{.ServiceLegend}}
+    </section>
+    {{end}}
+
+    <section class="trend-card">
+      <h2>🔁 Flapping Ports</h2>
+      {{if .Trend.Flapping}}
+      <div class="ports-table-wrap">
+        <table class="ports-table">
+          <thead><tr><th>Host</th><th>Port</th><th>Service</th>{{range .Labels}}<th>{{.}}</th>{{end}}</tr></thead>
+          <tbody>
+            {{range .Trend.Flapping}}
+            <tr>
+              <td><code>{{.Addr}}</code>{{if .Hostname}} <small class="muted">{{.Hostname}}</small>{{end}}</td>
+              <td class="p-port">{{.Port}}<small class="p-proto"> {{.Protocol}}</small></td>
+              <td class="p-service">{{or .Service "-"}}</td>
+              {{range .States}}<td>{{if eq . "open"}}<span class="flap-open">● open</span>{{else if eq . "down"}}<span class="flap-closed" title="Host not up in this scan">– down</span>{{else}}<span class="flap-closed">○</span>{{end}}</td>{{end}}
+            </tr>
+            {{end}}
+          </tbody>
+        </table>
+      </div>
+      {{else}}
+      <p class="muted">No ports opened and closed repeatedly across the series.</p>
+      {{end}}
+    </section>
+
+    <section class="trend-card">
+      <h2>📋 Scans</h2>
+      <div class="ports-table-wrap">
+        <table class="ports-table">
+          <thead><tr><th>Started</th><th>Source</th><th>Hosts Up</th><th>Open Ports</th></tr></thead>
+          <tbody>
+            {{range .Trend.Points}}
+            <tr>
+              <td>{{if .Started.IsZero}}-{{else}}{{.Started.Format "2006-01-02 15:04"}}{{end}}</td>
+              <td class="p-product">{{.Source}}</td>
+              <td>{{.HostsUp}}</td>
+              <td class="p-port">{{.OpenPorts}}</td>
+            </tr>
+            {{end}}
+          </tbody>
+        </table>
+      </div>
+    </section>
+
+    <footer class="footer">
+      <small class="muted">
+        🛡️ Trend report generated by <strong>DefenceLogic.io</strong> • Nmap HTML Converter v1.0.0<br/>
+        📅 Exported on {{.Generated.Format "Monday, January 2, 2006 at 15:04:05"}}
+      </small>
+    </footer>
+  </main>
+</body>
+</html>`
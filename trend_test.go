@@ -0,0 +1,64 @@
+package main
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLabelPoints(t *testing.T) {
+	day := time.Date(2025, 3, 4, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		started []time.Time
+		want    []string
+	}{
+		{"one per day", []time.Time{day, day.AddDate(0, 0, 1)}, []string{"Mar 4", "Mar 5"}},
+		{"same day", []time.Time{day, day.Add(6 * time.Hour)}, []string{"Mar 4 09:00", "Mar 4 15:00"}},
+		{"same minute", []time.Time{day, day.Add(time.Second)}, []string{"Mar 4 09:00 #1", "Mar 4 09:00 #2"}},
+	}
+	for _, tt := range tests {
+		points := make([]TrendPoint, len(tt.started))
+		for i, s := range tt.started {
+			points[i].Started = s
+		}
+		labelPoints(points)
+		var got []string
+		for _, p := range points {
+			got = append(got, p.Label)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: labels = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBuildTrendFlapping(t *testing.T) {
+	start := time.Date(2025, 3, 4, 9, 0, 0, 0, time.UTC)
+	scan := func(i int, hosts ...Host) seriesScan {
+		return seriesScan{
+			source: "scan.xml",
+			info:   NmapRunInfo{StartTime: strconv.FormatInt(start.Add(time.Duration(i)*time.Hour).Unix(), 10)},
+			hosts:  hosts,
+		}
+	}
+	ssh := func() Port { return testPort(22, "ssh") }
+	web := func() Port { return testPort(80, "http") }
+	scans := []seriesScan{
+		scan(0, testHost("10.0.0.1", "up", ssh()), testHost("10.0.0.2", "up", web())),
+		scan(1, testHost("10.0.0.1", "down"), testHost("10.0.0.2", "up")),
+		scan(2, testHost("10.0.0.1", "up", ssh()), testHost("10.0.0.2", "up", web())),
+	}
+	report := buildTrend(scans)
+
+	if len(report.Flapping) != 1 || report.Flapping[0].Addr != "10.0.0.2" {
+		t.Fatalf("flapping = %+v, want only 10.0.0.2:80 (10.0.0.1 was down, not closed)", report.Flapping)
+	}
+	if got, want := report.Flapping[0].States(), []string{"open", "closed", "open"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("states = %q, want %q", got, want)
+	}
+	if report.Points[1].HostsUp != 1 {
+		t.Errorf("hosts up in scan 2 = %d, want 1", report.Points[1].HostsUp)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// versionParts splits a product version such as "7.4p1" or "2.4.49" into
+// numeric and alphabetic runs: [7 4 p 1], [2 4 49]
+func versionParts(v string) []string {
+	var parts []string
+	var cur strings.Builder
+	kind := 0 // 1 digit run, 2 letter run
+	flush := func() {
+		if cur.Len() > 0 {
+			parts = append(parts, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(v) {
+		switch {
+		case unicode.IsDigit(r):
+			if kind != 1 {
+				flush()
+			}
+			kind = 1
+			cur.WriteRune(r)
+		case unicode.IsLetter(r):
+			if kind != 2 {
+				flush()
+			}
+			kind = 2
+			cur.WriteRune(r)
+		default:
+			flush()
+			kind = 0
+		}
+	}
+	flush()
+	return parts
+}
+
+// compareVersions returns -1, 0 or 1. Numeric runs compare numerically and
+// rank above letter runs, so 7.4 < 7.4p1 < 7.5 and 2.4.9 < 2.4.10.
+func compareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		if i >= len(pa) {
+			return -1
+		}
+		if i >= len(pb) {
+			return 1
+		}
+		na, errA := strconv.Atoi(pa[i])
+		nb, errB := strconv.Atoi(pb[i])
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				if na < nb {
+					return -1
+				}
+				return 1
+			}
+		case errA == nil:
+			return 1
+		case errB == nil:
+			return -1
+		default:
+			if c := strings.Compare(pa[i], pb[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
+
+// versionConstraint is one comparison such as ">= 2.4.0"
+type versionConstraint struct {
+	Op      string
+	Version string
+}
+
+// parseVersionConstraints parses a comma separated list like ">=2.4.0, <2.4.50".
+// A bare version means equality.
+func parseVersionConstraints(s string) ([]versionConstraint, error) {
+	var out []versionConstraint
+	for _, field := range splitList(s) {
+		op := "="
+		for _, candidate := range []string{">=", "<=", "!=", "==", ">", "<", "="} {
+			if strings.HasPrefix(field, candidate) {
+				op = candidate
+				field = field[len(candidate):]
+				break
+			}
+		}
+		if op == "==" {
+			op = "="
+		}
+		field = strings.TrimSpace(field)
+		if field == "" {
+			return nil, fmt.Errorf("missing version in constraint %q", s)
+		}
+		out = append(out, versionConstraint{Op: op, Version: field})
+	}
+	return out, nil
+}
+
+// versionSatisfies reports whether v meets every constraint. An unknown
+// version never satisfies a non-empty constraint list.
+func versionSatisfies(v string, constraints []versionConstraint) bool {
+	if len(constraints) == 0 {
+		return true
+	}
+	if v == "" {
+		return false
+	}
+	for _, c := range constraints {
+		cmp := compareVersions(v, c.Version)
+		ok := false
+		switch c.Op {
+		case "=":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package main
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"2.4.9", "2.4.10", -1},
+		{"2.4.49", "2.4.49", 0},
+		{"7.4", "7.4p1", -1},
+		{"7.4p1", "7.5", -1},
+		{"7.4p2", "7.4p1", 1},
+		{"9.0.31", "9.0.3", 1},
+		{"1.0rc1", "1.0.1", -1},
+		{"8.2", "10.0", -1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestVersionSatisfies(t *testing.T) {
+	tests := []struct {
+		version, constraints string
+		want                 bool
+	}{
+		{"2.4.49", ">=2.4.0, <2.4.50", true},
+		{"2.4.50", ">=2.4.0, <2.4.50", false},
+		{"2.4.49", "2.4.49", true},
+		{"2.4.49", "!= 2.4.49", false},
+		{"7.4p1", "<= 7.4", false},
+		{"", ">=1.0", false},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		c, err := parseVersionConstraints(tt.constraints)
+		if err != nil {
+			t.Fatalf("parseVersionConstraints(%q): %v", tt.constraints, err)
+		}
+		if got := versionSatisfies(tt.version, c); got != tt.want {
+			t.Errorf("versionSatisfies(%q, %q) = %v, want %v", tt.version, tt.constraints, got, tt.want)
+		}
+	}
+}
+
+func TestVersionHasPrefix(t *testing.T) {
+	tests := []struct {
+		version, cycle string
+		want           bool
+	}{
+		{"2.4.49", "2.4", true},
+		{"2.40.1", "2.4", false},
+		{"2.2", "2.2", true},
+		{"2", "2.2", false},
+	}
+	for _, tt := range tests {
+		if got := versionHasPrefix(tt.version, tt.cycle); got != tt.want {
+			t.Errorf("versionHasPrefix(%q, %q) = %v, want %v", tt.version, tt.cycle, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"strings"
+)
+
+// WebInfo summarises the HTTP scripts run against a web port
+type WebInfo struct {
+	URL          string         `json:"url"`
+	Title        string         `json:"title,omitempty"`
+	RedirectURL  string         `json:"redirect_url,omitempty"`
+	Server       string         `json:"server,omitempty"`
+	Methods      []string       `json:"methods,omitempty"`
+	RiskyMethods []string       `json:"risky_methods,omitempty"`
+	Headers      []HeaderStatus `json:"security_headers,omitempty"` // empty when http-headers did not run
+}
+
+// HeaderStatus is one security header and whether the response carried it
+type HeaderStatus struct {
+	Name    string `json:"name"`
+	Present bool   `json:"present"`
+	Value   string `json:"value,omitempty"`
+}
+
+// securityHeaders are checked on every response; HSTS only applies to HTTPS
+var securityHeaders = []string{"Strict-Transport-Security", "Content-Security-Policy", "X-Frame-Options", "X-Content-Type-Options"}
+
+// riskyMethods are flagged even when http-methods does not mark them
+var riskyMethods = []string{"PUT", "DELETE", "TRACE", "TRACK", "CONNECT"}
+
+// MissingHeaders lists the security headers the response lacked
+func (w WebInfo) MissingHeaders() []string {
+	var out []string
+	for _, h := range w.Headers {
+		if !h.Present {
+			out = append(out, h.Name)
+		}
+	}
+	return out
+}
+
+// analyzeWeb builds the HTTP summary for a port, nil when no HTTP script ran
+func analyzeWeb(addr string, p Port) *WebInfo {
+	var info WebInfo
+	found := false
+	for _, s := range p.Scripts {
+		switch s.ID {
+		case "http-title":
+			found = true
+			parseHTTPTitle(s, &info)
+		case "http-server-header":
+			found = true
+			if v := s.root().elem(""); v != "" {
+				info.Server = v
+			} else {
+				info.Server = firstLine(s.Output)
+			}
+		case "http-headers":
+			found = true
+			parseHTTPHeaders(s, &info)
+		case "http-methods":
+			found = true
+			parseHTTPMethods(s, &info)
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	info.URL = portURL(addr, p)
+	if info.URL == "" {
+		info.URL = "http://" + hostPort(addr, p.PortId)
+	}
+	if strings.HasPrefix(info.URL, "http://") {
+		headers := info.Headers[:0]
+		for _, h := range info.Headers {
+			if h.Name != "Strict-Transport-Security" {
+				headers = append(headers, h)
+			}
+		}
+		info.Headers = headers
+	}
+	for _, m := range info.Methods {
+		if containsString(riskyMethods, m) && !containsString(info.RiskyMethods, m) {
+			info.RiskyMethods = append(info.RiskyMethods, m)
+		}
+	}
+	if info.Server == "" {
+		info.Server = strings.TrimSpace(p.Service.Product + " " + p.Service.Version)
+	}
+	return &info
+}
+
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = trimScriptLine(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// parseHTTPTitle handles the title/redirect_url elems and the text forms
+// "Welcome" and "Did not follow redirect to https://..."
+func parseHTTPTitle(s Script, info *WebInfo) {
+	root := s.root()
+	info.Title = root.elem("title")
+	info.RedirectURL = root.elem("redirect_url")
+	if len(root.Elems) > 0 {
+		return
+	}
+	line := firstLine(s.Output)
+	switch {
+	case strings.HasPrefix(line, "Did not follow redirect to "):
+		info.RedirectURL = strings.TrimPrefix(line, "Did not follow redirect to ")
+	case strings.HasPrefix(line, "Site doesn't have a title"):
+	default:
+		info.Title = line
+	}
+	for _, l := range strings.Split(s.Output, "\n") {
+		l = trimScriptLine(l)
+		if strings.HasPrefix(l, "Requested resource was ") {
+			info.RedirectURL = strings.TrimPrefix(l, "Requested resource was ")
+		}
+	}
+}
+
+// parseHTTPHeaders reads "Name: value" lines, from elems or the output text
+func parseHTTPHeaders(s Script, info *WebInfo) {
+	var lines []string
+	for _, e := range s.Elems {
+		lines = append(lines, e.Value)
+	}
+	if len(lines) == 0 {
+		lines = strings.Split(s.Output, "\n")
+	}
+	received := make(map[string]string)
+	for _, l := range lines {
+		name, value, ok := strings.Cut(trimScriptLine(l), ":")
+		if !ok || strings.Contains(name, " ") {
+			continue
+		}
+		received[strings.ToLower(name)] = strings.TrimSpace(value)
+		if strings.EqualFold(name, "Server") && info.Server == "" {
+			info.Server = strings.TrimSpace(value)
+		}
+	}
+	info.Headers = nil
+	for _, h := range securityHeaders {
+		v, ok := received[strings.ToLower(h)]
+		info.Headers = append(info.Headers, HeaderStatus{Name: h, Present: ok, Value: v})
+	}
+}
+
+// parseHTTPMethods handles the "Supported Methods" and "Potentially risky
+// methods" tables or text lines
+func parseHTTPMethods(s Script, info *WebInfo) {
+	root := s.root()
+	if t, ok := root.table("Supported Methods"); ok {
+		for _, e := range t.Elems {
+			info.Methods = append(info.Methods, strings.TrimSpace(e.Value))
+		}
+		if t, ok := root.table("Potentially risky methods"); ok {
+			for _, e := range t.Elems {
+				info.RiskyMethods = append(info.RiskyMethods, strings.TrimSpace(e.Value))
+			}
+		}
+		return
+	}
+	for _, l := range strings.Split(s.Output, "\n") {
+		name, value, ok := strings.Cut(trimScriptLine(l), ":")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(name) {
+		case "Supported Methods":
+			info.Methods = append(info.Methods, strings.Fields(value)...)
+		case "Potentially risky methods":
+			info.RiskyMethods = append(info.RiskyMethods, strings.Fields(value)...)
+		}
+	}
+}
+
+// WebEntry places a web service on its host for the web inventory
+type WebEntry struct {
+	WebInfo
+	Location ReportFinding `json:"location"`
+}
+
+// hostWebApps lists the analysed web services on the host
+func hostWebApps(h Host) []WebEntry {
+	var out []WebEntry
+	for _, p := range h.Ports.Ports {
+		if p.Web == nil {
+			continue
+		}
+		out = append(out, WebEntry{WebInfo: *p.Web, Location: ReportFinding{
+			Addr:     h.PrimaryAddr(),
+			Hostname: h.PrimaryName(),
+			Port:     p.PortId,
+			Protocol: p.Protocol,
+		}})
+	}
+	return out
+}
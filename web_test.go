@@ -0,0 +1,80 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzeWebText(t *testing.T) {
+	p := testPort(443, "https")
+	p.Service.Product = "nginx"
+	p.Scripts = []Script{
+		{ID: "http-title", Output: "Did not follow redirect to https://10.0.0.1/login"},
+		{ID: "http-headers", Output: `
+  Server: nginx/1.18.0
+  Content-Type: text/html
+  X-Frame-Options: DENY
+  Strict-Transport-Security: max-age=31536000
+
+  (Request type: HEAD)`},
+		{ID: "http-methods", Output: `
+  Supported Methods: GET HEAD POST PUT OPTIONS
+  Potentially risky methods: PUT`},
+	}
+	info := analyzeWeb("10.0.0.1", p)
+	if info == nil {
+		t.Fatal("no web info")
+	}
+	if want := "https://" + hostPort("10.0.0.1", 443); info.URL != want {
+		t.Errorf("URL = %q, want %q", info.URL, want)
+	}
+	if info.RedirectURL != "https://10.0.0.1/login" || info.Title != "" {
+		t.Errorf("title = %q, redirect = %q", info.Title, info.RedirectURL)
+	}
+	if info.Server != "nginx/1.18.0" {
+		t.Errorf("server = %q, want nginx/1.18.0", info.Server)
+	}
+	if want := []string{"Content-Security-Policy", "X-Content-Type-Options"}; !reflect.DeepEqual(info.MissingHeaders(), want) {
+		t.Errorf("missing headers = %v, want %v", info.MissingHeaders(), want)
+	}
+	if want := []string{"GET", "HEAD", "POST", "PUT", "OPTIONS"}; !reflect.DeepEqual(info.Methods, want) {
+		t.Errorf("methods = %v, want %v", info.Methods, want)
+	}
+	if want := []string{"PUT"}; !reflect.DeepEqual(info.RiskyMethods, want) {
+		t.Errorf("risky methods = %v, want %v", info.RiskyMethods, want)
+	}
+}
+
+func TestAnalyzeWebTables(t *testing.T) {
+	p := testPort(80, "http")
+	p.Service.Product, p.Service.Version = "Apache httpd", "2.4.49"
+	p.Scripts = []Script{
+		{ID: "http-title", Elems: []ScriptElem{{Key: "title", Value: "Intranet"}}},
+		{ID: "http-methods", Tables: []ScriptTable{
+			{Key: "Supported Methods", Elems: []ScriptElem{{Value: "GET"}, {Value: "TRACE"}}},
+		}},
+		{ID: "http-headers", Elems: []ScriptElem{{Value: "Strict-Transport-Security: max-age=1"}}},
+	}
+	info := analyzeWeb("10.0.0.2", p)
+	if info == nil {
+		t.Fatal("no web info")
+	}
+	if info.Title != "Intranet" {
+		t.Errorf("title = %q, want Intranet", info.Title)
+	}
+	// HSTS is not checked over plain HTTP
+	for _, h := range info.Headers {
+		if h.Name == "Strict-Transport-Security" {
+			t.Errorf("HSTS checked on %s", info.URL)
+		}
+	}
+	if want := []string{"TRACE"}; !reflect.DeepEqual(info.RiskyMethods, want) {
+		t.Errorf("risky methods = %v, want %v", info.RiskyMethods, want)
+	}
+	if info.Server != "Apache httpd 2.4.49" {
+		t.Errorf("server = %q, want the service product", info.Server)
+	}
+	if analyzeWeb("10.0.0.2", testPort(22, "ssh")) != nil {
+		t.Error("web info for a port without HTTP scripts")
+	}
+}